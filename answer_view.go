@@ -0,0 +1,110 @@
+package main
+
+// AnswerStatus marks placeholders for answers the recipient may not read.
+type AnswerStatus string
+
+const AnswerSubmitted AnswerStatus = "submitted"
+
+type AnonymousAnswersPayload struct {
+	GameID    string `json:"gameId"`
+	Anonymous bool   `json:"anonymous"`
+}
+
+// AnswersFor filters answers down to what viewerId may see. The moderator
+// sees everything. Everybody else sees revealed answers and the answers of
+// their own team or their own, and only a placeholder for the others, which
+// does not say who answered if the game keeps answers anonymous. From the end
+// of the round until a vote on it is over, revealed answers of others do not
+// say who wrote them either, so players vote for answers and not for people.
+func AnswersFor(game Game, round GameRound, answers []Answer, viewerId string) []Answer {
+	if viewerId != "" && RoleOf(game, viewerId) == RoleModerator {
+		return answers
+	}
+
+	teamId := game.TeamIDOf(viewerId)
+	ended := round.State == RoundClosed || round.State == RoundRevealed
+	voting := ended && (round.Voting == nil || round.Voting.Open)
+	res := make([]Answer, 0, len(answers))
+
+	for _, a := range answers {
+		own := viewerId != "" && a.PlayerID == viewerId
+		ownTeam := teamId != "" && a.TeamID == teamId
+
+		if own || ownTeam {
+			res = append(res, a)
+			continue
+		}
+
+		if a.RevealedToPlayers {
+			if voting {
+				a.PlayerID = ""
+				a.TeamID = ""
+			}
+
+			res = append(res, a)
+			continue
+		}
+
+		placeholder := Answer{
+			ID:       a.ID,
+			GameID:   a.GameID,
+			RoundID:  a.RoundID,
+			PlayerID: a.PlayerID,
+			TeamID:   a.TeamID,
+			Status:   AnswerSubmitted,
+		}
+
+		if game.AnonymousAnswers {
+			placeholder.PlayerID = ""
+			placeholder.TeamID = ""
+		}
+
+		res = append(res, placeholder)
+	}
+
+	return res
+}
+
+// activeAnswers loads a game, its active round and the answers to it.
+func (h *Hub) activeAnswers(gameID string) (*Game, *GameRound, []Answer, error) {
+	game, err := h.store.FindGameById(gameID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	round, err := h.store.FindActiveRoundByGameId(gameID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	answers, err := h.store.FindAllAnswersByGameAndRound(gameID, round.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return game, round, answers, nil
+}
+
+func (c *Connection) SetAnonymousAnswers(msg SocketMessage) (*Game, error) {
+	var payload AnonymousAnswersPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	game, err := c.hub.store.FindGameById(payload.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	game.AnonymousAnswers = payload.Anonymous
+
+	err = c.hub.store.UpdateGame(*game)
+	if err != nil {
+		return nil, err
+	}
+
+	c.hub.BroadcastAnswers(game.ID)
+
+	return game, nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestAnswersForHidesAuthorsUntilVoteEnds(t *testing.T) {
+	game := Game{ID: "g1", ModeratorUUID: "mod", Players: []string{"p1", "p2"}}
+	answers := []Answer{
+		{ID: "a1", PlayerID: "p1", Text: "one", RevealedToPlayers: true},
+		{ID: "a2", PlayerID: "p2", TeamID: "t2", Text: "two", RevealedToPlayers: true},
+	}
+
+	tests := []struct {
+		name    string
+		state   RoundState
+		voting  *Voting
+		viewer  string
+		authors []string
+	}{
+		{"running round", RoundOpen, nil, "p1", []string{"p1", "p2"}},
+		{"closed round", RoundClosed, nil, "p1", []string{"p1", ""}},
+		{"revealed round", RoundRevealed, nil, "p1", []string{"p1", ""}},
+		{"open vote", RoundRevealed, &Voting{Open: true}, "p1", []string{"p1", ""}},
+		{"open vote, spectator", RoundRevealed, &Voting{Open: true}, "", []string{"", ""}},
+		{"open vote, moderator", RoundRevealed, &Voting{Open: true}, "mod", []string{"p1", "p2"}},
+		{"vote over", RoundRevealed, &Voting{Open: false}, "p1", []string{"p1", "p2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			round := GameRound{ID: "r1", GameID: "g1", State: tt.state, Voting: tt.voting}
+			view := AnswersFor(game, round, answers, tt.viewer)
+
+			for i, a := range view {
+				hidden := tt.authors[i] == ""
+				if a.PlayerID != tt.authors[i] || (a.TeamID == "") != (hidden || answers[i].TeamID == "") || a.Text != answers[i].Text {
+					t.Errorf("answer %s: author %q text %q, want author %q", a.ID, a.PlayerID, a.Text, tt.authors[i])
+				}
+			}
+		})
+	}
+}
+
+// hiddenAnswers are the unrevealed answers of newAnswerViewTestHub by author.
+var hiddenAnswers = map[string]string{
+	"p1": "first secret",
+	"p2": "second secret",
+}
+
+// newAnswerViewTestHub returns a hub with a game moderated by "mod" whose
+// open round has an unrevealed answer of "p1" and of "p2", and a revealed
+// one. "outsider" is not part of the game.
+func newAnswerViewTestHub(t *testing.T) (*Hub, Game) {
+	hub := newTestHub(t)
+	game := Game{ID: "g1", Code: "ABC234", ModeratorUUID: "mod", Players: []string{"p1", "p2"}}
+
+	hub.Do(func() {
+		for _, id := range []string{"mod", "p1", "p2", "outsider"} {
+			hub.store.CreatePlayer(Player{ID: id, Nickname: id})
+		}
+
+		hub.store.CreateGame(game)
+		hub.store.CreateRound(GameRound{ID: "r1", GameID: "g1", Round: 1, State: RoundOpen})
+		hub.store.CreateAnswer(Answer{ID: "a1", GameID: "g1", RoundID: "r1", PlayerID: "p1", Text: hiddenAnswers["p1"]})
+		hub.store.CreateAnswer(Answer{ID: "a2", GameID: "g1", RoundID: "r1", PlayerID: "p2", Text: hiddenAnswers["p2"]})
+		hub.store.CreateAnswer(Answer{ID: "a3", GameID: "g1", RoundID: "r1", PlayerID: "p2", Text: "public", RevealedToPlayers: true})
+	})
+
+	return hub, game
+}
+
+// assertNoLeak fails if data holds an unrevealed answer viewerId may not
+// read. Spectators view as "".
+func assertNoLeak(t *testing.T, where string, viewerId string, data []byte) {
+	t.Helper()
+
+	if viewerId == "mod" {
+		return
+	}
+
+	for author, text := range hiddenAnswers {
+		if author != viewerId && bytes.Contains(data, []byte(text)) {
+			t.Errorf("%s: %q got the answer of %s: %s", where, viewerId, author, data)
+		}
+	}
+}
+
+// drain returns what is queued for c.
+func drain(c *Connection) [][]byte {
+	res := [][]byte{}
+
+	for len(c.send) > 0 {
+		res = append(res, (<-c.send).data)
+	}
+
+	return res
+}
+
+func TestUnrevealedAnswersDoNotLeak(t *testing.T) {
+	hub, game := newAnswerViewTestHub(t)
+	viewers := []string{"mod", "p1", "p2", "outsider", ""}
+
+	members := map[string]*Connection{}
+	spectator := NewConnection(hub, nil, nil)
+
+	hub.Do(func() {
+		for _, id := range []string{"mod", "p1", "p2"} {
+			members[id] = NewConnection(hub, nil, &Session{PlayerID: id})
+			hub.JoinRoom(members[id], game)
+		}
+
+		hub.JoinRoomAsSpectator(spectator, game)
+	})
+
+	t.Run("broadcast", func(t *testing.T) {
+		hub.Do(func() {
+			for _, c := range members {
+				drain(c)
+			}
+			drain(spectator)
+
+			hub.BroadcastAnswers(game.ID)
+		})
+
+		members[""] = spectator
+		defer delete(members, "")
+
+		for id, c := range members {
+			sent := drain(c)
+			if len(sent) == 0 {
+				t.Fatalf("%q got no answers", id)
+			}
+
+			for _, data := range sent {
+				assertNoLeak(t, "all_answers", id, data)
+			}
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		var events []roomEvent
+		hub.Do(func() {
+			events = hub.rooms[game.ID].history
+		})
+
+		if len(events) == 0 {
+			t.Fatal("nothing was recorded")
+		}
+
+		for _, e := range events {
+			for _, viewerId := range viewers {
+				assertNoLeak(t, "replayed "+e.For(viewerId).Type, viewerId, e.For(viewerId).Payload)
+			}
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		for _, viewerId := range viewers {
+			var msg *Message
+			var err error
+			hub.Do(func() {
+				msg, err = hub.SnapshotMessage(game, viewerId)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertNoLeak(t, "snapshot", viewerId, msg.Payload)
+		}
+	})
+
+	t.Run("spectator snapshot", func(t *testing.T) {
+		var err error
+		hub.Do(func() {
+			drain(spectator)
+			err = spectator.sendSpectatorSnapshot(game)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sent := drain(spectator)
+		if len(sent) == 0 {
+			t.Fatal("nothing was sent")
+		}
+
+		for _, data := range sent {
+			assertNoLeak(t, "spectator snapshot", "", data)
+		}
+	})
+
+	t.Run("rest", func(t *testing.T) {
+		ts := newTestServer(t, hub)
+
+		for _, viewerId := range []string{"mod", "p1", "p2"} {
+			token, _, err := hub.sessions.Issue(viewerId)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/games/g1/answers", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("%s: %s %s", viewerId, resp.Status, body)
+			}
+
+			if viewerId == "mod" && !bytes.Contains(body, []byte(hiddenAnswers["p1"])) {
+				t.Fatalf("the moderator does not see the answers: %s", body)
+			}
+
+			assertNoLeak(t, "GET answers", viewerId, body)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// The REST API runs the same commands as the websocket, on a connection
+// without a socket that belongs to the player of the request's session
+// token. Commands are authorized the same way and broadcast their changes
+// to connected clients as usual. Clients get their token by creating a
+// player first.
+
+// RegisterAPI adds the REST API to group.
+func (s *Server) RegisterAPI(group *gin.RouterGroup) {
+	group.POST("/players", s.CreatePlayer)
+
+	group.GET("/games", s.command("get_games", "get_games", http.StatusOK))
+	group.POST("/games", s.command("create_game", "", http.StatusCreated))
+	group.GET("/games/:gameId", s.command("get_game", "get_game", http.StatusOK))
+	group.DELETE("/games/:gameId", s.command("delete_game", "", http.StatusOK))
+	group.PUT("/games/:gameId/privacy", s.command("set_game_privacy", "", http.StatusOK))
+	group.PUT("/games/:gameId/anonymous-answers", s.command("set_anonymous_answers", "", http.StatusOK))
+	group.PUT("/games/:gameId/presence-policy", s.command("set_presence_policy", "", http.StatusOK))
+	group.GET("/games/:gameId/leaderboard", s.command("get_leaderboard", "leaderboard", http.StatusOK))
+
+	group.POST("/games/:gameId/join", s.command("join_game", "", http.StatusOK))
+	group.POST("/games/:gameId/leave", s.command("leave_game", "", http.StatusOK))
+	group.GET("/games/:gameId/players", s.command("get_presence", "presence", http.StatusOK))
+	group.GET("/games/:gameId/players/connected", s.command("get_connected_players", "get_connected_players", http.StatusOK))
+
+	group.GET("/games/:gameId/rounds", s.command("get_rounds", "get_rounds", http.StatusOK))
+	group.POST("/games/:gameId/rounds/next", s.command("go_next_round", "", http.StatusOK))
+	group.POST("/games/:gameId/rounds/current/start", s.command("start_round", "", http.StatusOK))
+	group.POST("/games/:gameId/rounds/current/end", s.command("end_round", "", http.StatusOK))
+	group.POST("/games/:gameId/rounds/current/reveal", s.command("reveal_round", "", http.StatusOK))
+	group.PUT("/games/:gameId/rounds/current/options", s.command("set_choice_options", "", http.StatusOK))
+	group.PUT("/games/:gameId/rounds/current/answer-mode", s.command("set_answer_mode", "", http.StatusOK))
+	group.POST("/games/:gameId/rounds/current/voting/start", s.command("start_voting", "", http.StatusOK))
+	group.POST("/games/:gameId/rounds/current/voting/end", s.command("end_voting", "", http.StatusOK))
+
+	group.POST("/games/:gameId/buzzer/open", s.command("open_buzzer", "", http.StatusOK))
+	group.POST("/games/:gameId/buzzer/buzz", s.command("buzz", "", http.StatusOK))
+	group.POST("/games/:gameId/buzzer/reset", s.command("reset_buzzer", "", http.StatusOK))
+	group.POST("/games/:gameId/buzzer/:playerId/accept", s.command("accept_buzz", "", http.StatusOK))
+	group.POST("/games/:gameId/buzzer/:playerId/reject", s.command("reject_buzz", "", http.StatusOK))
+
+	group.POST("/games/:gameId/teams", s.command("create_team", "", http.StatusCreated))
+	group.DELETE("/games/:gameId/teams/:teamId", s.command("delete_team", "", http.StatusOK))
+	group.PUT("/games/:gameId/teams/:teamId/captain", s.command("set_team_captain", "", http.StatusOK))
+	group.POST("/games/:gameId/teams/shuffle", s.command("shuffle_teams", "", http.StatusOK))
+	group.GET("/games/:gameId/teams/leaderboard", s.command("get_team_leaderboard", "team_leaderboard", http.StatusOK))
+	group.PUT("/games/:gameId/players/:playerId/team", s.command("assign_player", "", http.StatusOK))
+
+	group.GET("/games/:gameId/question", s.command("get_text", "set_text", http.StatusOK))
+	group.PUT("/games/:gameId/question", s.command("set_text", "", http.StatusOK))
+	group.PUT("/games/:gameId/question-set", s.command("attach_question_set", "", http.StatusOK))
+	group.PUT("/games/:gameId/accepted-answers", s.command("set_accepted_answers", "", http.StatusOK))
+
+	group.GET("/games/:gameId/answers", s.command("get_answers", "all_answers", http.StatusOK))
+	group.POST("/games/:gameId/answers", s.command("set_answer", "", http.StatusOK))
+	group.POST("/games/:gameId/choice", s.command("choose", "", http.StatusOK))
+	group.POST("/answers/:answerId/reveal", s.command("set_answer_visible", "", http.StatusOK))
+	group.POST("/answers/:answerId/hide", s.command("set_answer_invisible", "", http.StatusOK))
+	group.DELETE("/answers/:answerId", s.command("delete_answer", "", http.StatusOK))
+	group.PUT("/answers/:answerId/points", s.command("set_answer_points", "", http.StatusOK))
+	group.PUT("/answers/:answerId/verdict", s.command("set_answer_verdict", "", http.StatusOK))
+	group.POST("/answers/:answerId/vote", s.command("vote", "", http.StatusOK))
+}
+
+// command handles a request with the websocket command of the same name.
+// The JSON body and the path parameters make up its payload. Commands that
+// send their result as a message rather than acknowledge it with one name
+// that message type as reply. Requests without any result get no content.
+func (s *Server) command(command string, reply string, status int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := s.requireSession(c, command)
+		if !ok {
+			return
+		}
+
+		payload := map[string]any{}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err == nil && len(body) > 0 {
+			err = json.Unmarshal(body, &payload)
+		}
+
+		if err != nil {
+			abortWithError(c, command, InvalidPayload(err))
+			return
+		}
+
+		// A body of null leaves nothing to add the path parameters to.
+		if payload == nil {
+			payload = map[string]any{}
+		}
+
+		for _, param := range c.Params {
+			payload[param.Key] = param.Value
+		}
+
+		data, err := json.Marshal(payload)
+		if err != nil {
+			abortWithError(c, command, err)
+			return
+		}
+
+		result, sent, err := s.execute(session, SocketMessage{
+			Version: ProtocolVersion,
+			Type:    command,
+			Payload: data,
+		})
+		if err != nil {
+			abortWithError(c, command, err)
+			return
+		}
+
+		if result != nil {
+			c.JSON(status, result)
+			return
+		}
+
+		for i := len(sent) - 1; i >= 0 && reply != ""; i-- {
+			if sent[i].Type == reply {
+				c.Data(status, gin.MIMEJSON, sent[i].Payload)
+				return
+			}
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// execute runs msg for the session's player on a connection without a
+// socket, and returns its result and the messages sent to the connection
+// itself. The connection leaves every room it joined before it is dropped,
+// and the player's websocket connections follow the games they joined or
+// left instead.
+func (s *Server) execute(session *Session, msg SocketMessage) (any, []SocketMessage, error) {
+	conn := NewConnection(s.hub, nil, session)
+	conn.PlayerID = &session.PlayerID
+
+	var result any
+	var err error
+
+	msg = s.hub.preparePassword(msg)
+
+	s.hub.Do(func() {
+		result, err = conn.Execute(msg)
+
+		for _, room := range conn.rooms {
+			room.Leave(conn)
+		}
+
+		conn.Close()
+		s.hub.SyncRooms(session.PlayerID)
+	})
+
+	sent := []SocketMessage{}
+
+	for out := range conn.send {
+		var m SocketMessage
+		if json.Unmarshal(out.data, &m) == nil {
+			sent = append(sent, m)
+		}
+	}
+
+	return result, sent, err
+}
+
+type CreatePlayerPayload struct {
+	Name string `json:"name"`
+}
+
+// CreatePlayerResponse is a new player along with the session token that
+// identifies them in later requests.
+type CreatePlayerResponse struct {
+	SessionPayload
+	Player Player `json:"player"`
+}
+
+// CreatePlayer makes a new player and issues them a session token. It is the
+// one request that needs no token.
+func (s *Server) CreatePlayer(c *gin.Context) {
+	var payload CreatePlayerPayload
+	err := c.ShouldBindJSON(&payload)
+	if err != nil {
+		abortWithError(c, "create_player", InvalidPayload(err))
+		return
+	}
+
+	player := Player{
+		ID:       uuid.New().String(),
+		Nickname: strings.TrimSpace(payload.Name),
+	}
+
+	if player.Nickname == "" {
+		abortWithError(c, "create_player", NewCommandError(ErrCodeInvalidPayload, "name is required"))
+		return
+	}
+
+	s.hub.Do(func() {
+		err = s.hub.store.CreatePlayer(player)
+	})
+
+	if err != nil {
+		abortWithError(c, "create_player", err)
+		return
+	}
+
+	token, session, err := s.hub.sessions.Issue(player.ID)
+	if err != nil {
+		abortWithError(c, "create_player", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreatePlayerResponse{
+		SessionPayload: SessionPayload{
+			Token:     token,
+			PlayerID:  session.PlayerID,
+			ExpiresAt: session.ExpiresAt,
+		},
+		Player: player,
+	})
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// apiRequest sends body as JSON to url, with token if there is one.
+func apiRequest(t *testing.T, method string, url string, token string, body any) *http.Response {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func post(t *testing.T, url string, token string, body any) *http.Response {
+	t.Helper()
+
+	return apiRequest(t, http.MethodPost, url, token, body)
+}
+
+// createPlayer creates a player through the API and returns their token.
+func createPlayer(t *testing.T, ts *httptest.Server, name string) CreatePlayerResponse {
+	t.Helper()
+
+	resp := post(t, ts.URL+"/api/v1/players", "", CreatePlayerPayload{Name: name})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create player: %s", resp.Status)
+	}
+
+	var created CreatePlayerResponse
+	err := json.NewDecoder(resp.Body).Decode(&created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return created
+}
+
+// createGame creates a game through the API for the player of token.
+func createGame(t *testing.T, ts *httptest.Server, token string) Game {
+	t.Helper()
+
+	resp := post(t, ts.URL+"/api/v1/games", token, CreateGamePayload{Name: "Quiz"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create game: %s", resp.Status)
+	}
+
+	var game Game
+	err := json.NewDecoder(resp.Body).Decode(&game)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return game
+}
+
+func TestCreatePlayerIssuesToken(t *testing.T) {
+	ts := newTestServer(t, newTestHub(t))
+
+	resp := post(t, ts.URL+"/api/v1/players", "", CreatePlayerPayload{Name: " Quizmaster "})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create player: %s", resp.Status)
+	}
+
+	var created CreatePlayerResponse
+	err := json.NewDecoder(resp.Body).Decode(&created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Token == "" || created.PlayerID != created.Player.ID || created.Player.Nickname != "Quizmaster" {
+		t.Fatalf("created %+v", created)
+	}
+
+	// The token is all it takes to use the rest of the API.
+	resp = post(t, ts.URL+"/api/v1/games", created.Token, CreateGamePayload{Name: "Quiz"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create game: %s", resp.Status)
+	}
+
+	var game Game
+	err = json.NewDecoder(resp.Body).Decode(&game)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if game.ModeratorUUID != created.Player.ID {
+		t.Fatalf("game moderated by %s, want %s", game.ModeratorUUID, created.Player.ID)
+	}
+
+	for _, body := range []any{CreatePlayerPayload{Name: "  "}, "not an object"} {
+		resp = post(t, ts.URL+"/api/v1/players", "", body)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("create player with %v: %s, want 400", body, resp.Status)
+		}
+	}
+}
+
+// Changes made through the API reach the game's websocket clients.
+func TestAPIBroadcastsToWebsocketClients(t *testing.T) {
+	hub := newTestHub(t)
+	ts := newTestServer(t, hub)
+
+	mod := createPlayer(t, ts, "Mod")
+	game := createGame(t, ts, mod.Token)
+	gameURL := ts.URL + "/api/v1/games/" + game.ID
+
+	client := connect(t, hub, nil)
+	defer client.close()
+	clientID := client.mustHello("Player")
+	client.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+
+	resp := apiRequest(t, http.MethodPut, gameURL+"/question", mod.Token, TextPayload{Text: "Why?"})
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("set question: %s", resp.Status)
+	}
+
+	_, ok := client.waitFor(func(m SocketMessage) bool {
+		var text string
+		return m.Type == "set_text" && json.Unmarshal(m.Payload, &text) == nil && text == "Why?"
+	})
+	if !ok {
+		t.Fatal("the client got no set_text with the new question")
+	}
+
+	for _, step := range []string{"/rounds/current/start", "/buzzer/open"} {
+		resp = post(t, gameURL+step, mod.Token, nil)
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("%s: %s", step, resp.Status)
+		}
+	}
+
+	client.mustRequest("buzz", GameIDPayload{GameID: game.ID}, nil)
+
+	resp = post(t, gameURL+"/buzzer/"+clientID+"/accept", mod.Token, nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("accept buzz: %s", resp.Status)
+	}
+
+	_, ok = client.waitFor(func(m SocketMessage) bool {
+		var buzzer Buzzer
+		return m.Type == "buzzer" && json.Unmarshal(m.Payload, &buzzer) == nil &&
+			len(buzzer.Queue) == 1 && buzzer.Queue[0].Status == BuzzAccepted
+	})
+	if !ok {
+		t.Fatal("the client got no buzzer with the accepted buzz")
+	}
+}
+
+func TestAPIRequiresModerator(t *testing.T) {
+	hub := newTestHub(t)
+	ts := newTestServer(t, hub)
+
+	mod := createPlayer(t, ts, "Mod")
+	game := createGame(t, ts, mod.Token)
+	gameURL := ts.URL + "/api/v1/games/" + game.ID
+
+	player := createPlayer(t, ts, "Player")
+
+	resp := post(t, gameURL+"/join", player.Token, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("join: %s", resp.Status)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{http.MethodPut, "/question", TextPayload{Text: "Why?"}},
+		{http.MethodPost, "/rounds/current/start", nil},
+		{http.MethodPost, "/buzzer/open", nil},
+		{http.MethodPost, "/teams", CreateTeamPayload{Name: "Red"}},
+	}
+
+	for _, tt := range tests {
+		resp := apiRequest(t, tt.method, gameURL+tt.path, player.Token, tt.body)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s: %s, want 403", tt.method, tt.path, resp.Status)
+		}
+	}
+
+	var question string
+	hub.Do(func() {
+		round, err := hub.store.FindActiveRoundByGameId(game.ID)
+		if err == nil {
+			question = round.Question
+		}
+	})
+
+	if question == "Why?" {
+		t.Fatal("a player changed the question")
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrBuzzerClosed  = NewCommandError(ErrCodeBuzzerClosed, "the buzzer is not open")
+	ErrAlreadyBuzzed = NewCommandError(ErrCodeAlreadyBuzzed, "you already buzzed")
+	ErrBuzzNotFound  = NewCommandError(ErrCodeBuzzNotFound, "player has not buzzed")
+)
+
+type BuzzStatus string
+
+const (
+	BuzzPending  BuzzStatus = "pending"
+	BuzzAccepted BuzzStatus = "accepted"
+	BuzzRejected BuzzStatus = "rejected"
+)
+
+type Buzz struct {
+	PlayerID string `json:"playerId"`
+	// OffsetMs is the time between opening the buzzer and the buzz reaching
+	// the hub, measured with the server's monotonic clock.
+	OffsetMs int64      `json:"offsetMs"`
+	Status   BuzzStatus `json:"status"`
+}
+
+// Buzzer is the buzzer of a game's current round. Buzzes are ordered by when
+// the hub handles them; the hub handles one message at a time, so
+// concurrent buzzes still end up in one well defined order. Buzzers only
+// live in memory.
+type Buzzer struct {
+	RoundID string `json:"roundId"`
+	Open    bool   `json:"open"`
+	// Limit is how many pending buzzes lock the buzzer.
+	Limit    int    `json:"limit"`
+	Queue    []Buzz `json:"queue"`
+	openedAt time.Time
+}
+
+func (b *Buzzer) pending() int {
+	n := 0
+
+	for _, buzz := range b.Queue {
+		if buzz.Status == BuzzPending {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (b *Buzzer) find(playerId string) int {
+	return slices.IndexFunc(b.Queue, func(buzz Buzz) bool {
+		return buzz.PlayerID == playerId
+	})
+}
+
+type OpenBuzzerPayload struct {
+	Limit int `json:"limit"`
+}
+
+func (p *OpenBuzzerPayload) setLegacy(s string) {
+	p.Limit, _ = strconv.Atoi(s)
+}
+
+type PlayerIDPayload struct {
+	PlayerID string `json:"playerId"`
+}
+
+func (p *PlayerIDPayload) setLegacy(s string) {
+	p.PlayerID = s
+}
+
+func (h *Hub) BroadcastBuzzer(gameID string) {
+	buzzer, ok := h.buzzers[gameID]
+	if !ok {
+		return
+	}
+
+	msg, err := NewMessage("buzzer", buzzer)
+	if err != nil {
+		log.Println("build buzzer:", err)
+		return
+	}
+
+	h.Broadcast(gameID, msg)
+}
+
+// CloseBuzzer drops the buzzer of a game when its round ends.
+func (h *Hub) CloseBuzzer(gameID string) {
+	buzzer, ok := h.buzzers[gameID]
+	if !ok {
+		return
+	}
+
+	buzzer.Open = false
+	h.BroadcastBuzzer(gameID)
+	delete(h.buzzers, gameID)
+}
+
+// activeBuzzer returns the buzzer of the connection's active game.
+func (c *Connection) activeBuzzer() (*Buzzer, string, error) {
+	game, err := c.GetActiveGame()
+	if err != nil {
+		return nil, "", err
+	}
+
+	buzzer, ok := c.hub.buzzers[game.ID]
+	if !ok {
+		return nil, "", ErrBuzzerClosed
+	}
+
+	return buzzer, game.ID, nil
+}
+
+// OpenBuzzer starts a fresh buzzer for the open active round. Limit is the
+// number of buzzes taken before the buzzer locks, one if left out.
+func (c *Connection) OpenBuzzer(msg SocketMessage) (*Buzzer, error) {
+	var payload OpenBuzzerPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	if payload.Limit < 0 {
+		return nil, NewCommandError(ErrCodeInvalidPayload, "limit must not be negative")
+	}
+
+	round, err := c.GetActiveRound()
+	if err != nil {
+		return nil, err
+	}
+
+	err = round.AcceptsAnswers(c.hub.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	buzzer := &Buzzer{
+		RoundID:  round.ID,
+		Open:     true,
+		Limit:    max(payload.Limit, 1),
+		Queue:    []Buzz{},
+		openedAt: c.hub.clock.Now(),
+	}
+
+	c.hub.buzzers[round.GameID] = buzzer
+	c.hub.BroadcastBuzzer(round.GameID)
+
+	return buzzer, nil
+}
+
+func (c *Connection) Buzz() error {
+	player, err := c.GetPlayer()
+	if err != nil {
+		return err
+	}
+
+	buzzer, gameID, err := c.activeBuzzer()
+	if err != nil {
+		return err
+	}
+
+	if !buzzer.Open {
+		return ErrBuzzerClosed
+	}
+
+	if buzzer.find(player.ID) >= 0 {
+		return ErrAlreadyBuzzed
+	}
+
+	buzzer.Queue = append(buzzer.Queue, Buzz{
+		PlayerID: player.ID,
+		OffsetMs: c.hub.clock.Now().Sub(buzzer.openedAt).Milliseconds(),
+		Status:   BuzzPending,
+	})
+
+	if buzzer.pending() >= buzzer.Limit {
+		buzzer.Open = false
+	}
+
+	c.hub.BroadcastBuzzer(gameID)
+
+	return nil
+}
+
+// AcceptBuzz settles the buzzer in favour of a player. The buzzer stays
+// closed until it is reset.
+func (c *Connection) AcceptBuzz(msg SocketMessage) error {
+	return c.settleBuzz(msg, BuzzAccepted)
+}
+
+// RejectBuzz rules out a player and opens the buzzer again for everyone who
+// has not buzzed yet.
+func (c *Connection) RejectBuzz(msg SocketMessage) error {
+	return c.settleBuzz(msg, BuzzRejected)
+}
+
+func (c *Connection) settleBuzz(msg SocketMessage, status BuzzStatus) error {
+	var payload PlayerIDPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return InvalidPayload(err)
+	}
+
+	buzzer, gameID, err := c.activeBuzzer()
+	if err != nil {
+		return err
+	}
+
+	i := buzzer.find(payload.PlayerID)
+	if i < 0 || buzzer.Queue[i].Status != BuzzPending {
+		return ErrBuzzNotFound
+	}
+
+	buzzer.Queue[i].Status = status
+
+	if status == BuzzAccepted {
+		buzzer.Open = false
+	} else {
+		buzzer.Open = buzzer.pending() < buzzer.Limit
+	}
+
+	c.hub.BroadcastBuzzer(gameID)
+
+	return nil
+}
+
+// ResetBuzzer clears the queue and opens the buzzer for everybody again.
+func (c *Connection) ResetBuzzer() error {
+	buzzer, gameID, err := c.activeBuzzer()
+	if err != nil {
+		return err
+	}
+
+	buzzer.Open = true
+	buzzer.Queue = []Buzz{}
+	buzzer.openedAt = c.hub.clock.Now()
+
+	c.hub.BroadcastBuzzer(gameID)
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type buzzerGame struct {
+	hub     *Hub
+	clock   *manualClock
+	mod     *testClient
+	players []*testClient
+	ids     []string
+	gameID  string
+}
+
+// startBuzzerGame starts a round with the given number of players and opens
+// the buzzer with limit.
+func startBuzzerGame(t *testing.T, players int, limit int) buzzerGame {
+	hub := newTestHub(t)
+	g := buzzerGame{hub: hub, clock: useManualClock(hub)}
+
+	g.mod = connect(t, hub, nil)
+	t.Cleanup(g.mod.close)
+	g.mod.mustHello("Mod")
+
+	var game Game
+	g.mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &game)
+	g.gameID = game.ID
+
+	for i := range players {
+		p := connect(t, hub, nil)
+		t.Cleanup(p.close)
+
+		g.ids = append(g.ids, p.mustHello("Player "+string(rune('A'+i))))
+		p.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+		g.players = append(g.players, p)
+	}
+
+	g.mod.mustRequest("start_round", GameIDPayload{GameID: game.ID}, nil)
+	g.mod.mustRequest("open_buzzer", map[string]any{"gameId": game.ID, "limit": limit}, nil)
+
+	return g
+}
+
+func (g buzzerGame) buzz(i int) error {
+	_, err := g.players[i].request("buzz", GameIDPayload{GameID: g.gameID})
+	return err
+}
+
+func (g buzzerGame) buzzer(t *testing.T) Buzzer {
+	var buzzer Buzzer
+	var ok bool
+
+	g.hub.Do(func() {
+		var b *Buzzer
+		if b, ok = g.hub.buzzers[g.gameID]; ok {
+			buzzer = *b
+			buzzer.Queue = slices.Clone(b.Queue)
+		}
+	})
+
+	if !ok {
+		t.Fatal("the game has no buzzer")
+	}
+
+	return buzzer
+}
+
+// queue lists the players in the buzzer's queue as indexes into g.ids.
+func (g buzzerGame) queue(t *testing.T) []int {
+	res := []int{}
+	for _, buzz := range g.buzzer(t).Queue {
+		res = append(res, slices.Index(g.ids, buzz.PlayerID))
+	}
+
+	return res
+}
+
+func wantCode(t *testing.T, err error, code ErrorCode) {
+	t.Helper()
+
+	var reply *replyError
+	if !errors.As(err, &reply) || reply.Code != code {
+		t.Fatalf("got %v, want %s", err, code)
+	}
+}
+
+func TestBuzzesKeepArrivalOrder(t *testing.T) {
+	g := startBuzzerGame(t, 3, 3)
+
+	for _, i := range []int{2, 0, 1} {
+		err := g.buzz(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		advance(g.hub, g.clock, 250*time.Millisecond)
+	}
+
+	buzzer := g.buzzer(t)
+
+	if got := g.queue(t); !slices.Equal(got, []int{2, 0, 1}) {
+		t.Fatalf("queue %v, want [2 0 1]", got)
+	}
+
+	for i, buzz := range buzzer.Queue {
+		if buzz.OffsetMs != int64(i*250) || buzz.Status != BuzzPending {
+			t.Fatalf("buzz %d: %+v", i, buzz)
+		}
+	}
+}
+
+// Concurrent buzzes end up in one order that everybody sees the same way.
+func TestConcurrentBuzzesHaveOneOrder(t *testing.T) {
+	const players = 12
+	g := startBuzzerGame(t, players, players)
+
+	var wg sync.WaitGroup
+	for i := range players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := g.buzz(i)
+			if err != nil {
+				t.Errorf("player %d: %s", i, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	queue := g.queue(t)
+	if len(queue) != players || len(slices.Compact(slices.Sorted(slices.Values(queue)))) != players {
+		t.Fatalf("queue %v, want every player once", queue)
+	}
+
+	for _, c := range append([]*testClient{g.mod}, g.players...) {
+		// Replies come after the broadcasts queued before them.
+		c.mustRequest("get_text", GameIDPayload{GameID: g.gameID}, nil)
+
+		// Every broadcast extends the previous one, so clients see the
+		// buzzes arrive in the final order.
+		seen := []string{}
+		for _, msg := range c.messages("buzzer") {
+			var b Buzzer
+			err := msg.Decode(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := []string{}
+			for _, buzz := range b.Queue {
+				ids = append(ids, buzz.PlayerID)
+			}
+
+			if len(ids) < len(seen) || !slices.Equal(ids[:len(seen)], seen) {
+				t.Fatalf("buzzer went from %v to %v", seen, ids)
+			}
+
+			seen = ids
+		}
+
+		if len(seen) != players {
+			t.Fatalf("last buzzer has %d buzzes, want %d", len(seen), players)
+		}
+
+		for i, id := range seen {
+			if id != g.ids[queue[i]] {
+				t.Fatalf("a client saw the buzzes in another order")
+			}
+		}
+	}
+}
+
+func TestBuzzerLocksAtLimit(t *testing.T) {
+	g := startBuzzerGame(t, 3, 2)
+
+	for i := range 2 {
+		err := g.buzz(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if g.buzzer(t).Open {
+		t.Fatal("the buzzer is still open with two pending buzzes")
+	}
+
+	wantCode(t, g.buzz(2), ErrCodeBuzzerClosed)
+	wantCode(t, g.buzz(0), ErrCodeBuzzerClosed)
+
+	if got := g.queue(t); !slices.Equal(got, []int{0, 1}) {
+		t.Fatalf("queue %v, want [0 1]", got)
+	}
+}
+
+func TestRejectBuzzReopensBuzzer(t *testing.T) {
+	g := startBuzzerGame(t, 2, 1)
+
+	err := g.buzz(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCode(t, g.buzz(1), ErrCodeBuzzerClosed)
+
+	g.mod.mustRequest("reject_buzz", map[string]any{"gameId": g.gameID, "playerId": g.ids[0]}, nil)
+
+	if !g.buzzer(t).Open {
+		t.Fatal("the buzzer is still closed after rejecting the only buzz")
+	}
+
+	wantCode(t, g.buzz(0), ErrCodeAlreadyBuzzed)
+
+	err = g.buzz(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buzzer := g.buzzer(t)
+	if buzzer.Open || buzzer.Queue[0].Status != BuzzRejected || buzzer.Queue[1].Status != BuzzPending {
+		t.Fatalf("buzzer %+v", buzzer)
+	}
+
+	// Only pending buzzes can be settled.
+	_, err = g.mod.request("accept_buzz", map[string]any{"gameId": g.gameID, "playerId": g.ids[0]})
+	wantCode(t, err, ErrCodeBuzzNotFound)
+
+	g.mod.mustRequest("accept_buzz", map[string]any{"gameId": g.gameID, "playerId": g.ids[1]}, nil)
+
+	if buzzer := g.buzzer(t); buzzer.Open || buzzer.Queue[1].Status != BuzzAccepted {
+		t.Fatalf("buzzer %+v", buzzer)
+	}
+}
+
+func TestResetBuzzerClearsState(t *testing.T) {
+	g := startBuzzerGame(t, 2, 1)
+
+	err := g.buzz(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.mod.mustRequest("accept_buzz", map[string]any{"gameId": g.gameID, "playerId": g.ids[0]}, nil)
+
+	advance(g.hub, g.clock, time.Second)
+	g.mod.mustRequest("reset_buzzer", GameIDPayload{GameID: g.gameID}, nil)
+
+	buzzer := g.buzzer(t)
+	if !buzzer.Open || len(buzzer.Queue) != 0 || buzzer.Limit != 1 {
+		t.Fatalf("buzzer after reset %+v", buzzer)
+	}
+
+	advance(g.hub, g.clock, 100*time.Millisecond)
+
+	err = g.buzz(0)
+	if err != nil {
+		t.Fatalf("buzzing again after the reset: %s", err)
+	}
+
+	if buzz := g.buzzer(t).Queue[0]; buzz.OffsetMs != 100 {
+		t.Fatalf("offset %d, want it counted from the reset", buzz.OffsetMs)
+	}
+}
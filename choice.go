@@ -0,0 +1,257 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RoundType decides how players answer a round.
+type RoundType string
+
+const (
+	// RoundText rounds take free text answers. Rounds stored before there
+	// were round types have no type and are text rounds too.
+	RoundText   RoundType = "text"
+	RoundChoice RoundType = "choice"
+)
+
+const (
+	minChoiceOptions = 2
+	maxChoiceOptions = 8
+)
+
+var ErrWrongRoundType = NewCommandError(ErrCodeWrongRoundType, "the round does not take this kind of answer")
+
+// IsChoice reports whether the round is a multiple choice round.
+func (r GameRound) IsChoice() bool {
+	return r.Type == RoundChoice
+}
+
+// AnswerText is the text an answer is judged by: what the player typed, or
+// the option they picked.
+func (r GameRound) AnswerText(a Answer) string {
+	if r.IsChoice() && a.Choice != nil && *a.Choice < len(r.Options) {
+		return r.Options[*a.Choice]
+	}
+
+	return a.Text
+}
+
+type ChoiceOptionsPayload struct {
+	Options []string `json:"options"`
+}
+
+type ChoicePayload struct {
+	Option int `json:"option"`
+}
+
+func (p *ChoicePayload) setLegacy(s string) {
+	p.Option, _ = strconv.Atoi(s)
+}
+
+// ChoiceResult is the tally of one option of a multiple choice round.
+type ChoiceResult struct {
+	Option  int      `json:"option"`
+	Text    string   `json:"text"`
+	Count   int      `json:"count"`
+	Players []string `json:"players"`
+}
+
+type ChoiceResultsPayload struct {
+	RoundID string         `json:"roundId"`
+	Results []ChoiceResult `json:"results"`
+}
+
+// SetChoiceOptions turns the active round into a multiple choice round with
+// the given options, or back into a text round when there are none. Options
+// can only change before the round opens.
+func (c *Connection) SetChoiceOptions(msg SocketMessage) (*GameRound, error) {
+	var payload ChoiceOptionsPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	round, err := c.GetActiveRound()
+	if err != nil {
+		return nil, err
+	}
+
+	if round.State != RoundDraft {
+		return nil, NewCommandError(ErrCodeInvalidRoundState, "options can only be changed before the round starts")
+	}
+
+	if len(payload.Options) == 0 {
+		round.Type = RoundText
+		round.Options = nil
+	} else {
+		if len(payload.Options) < minChoiceOptions || len(payload.Options) > maxChoiceOptions {
+			return nil, NewCommandError(ErrCodeInvalidPayload, "a choice round needs %d to %d options", minChoiceOptions, maxChoiceOptions)
+		}
+
+		options := []string{}
+		for i, o := range payload.Options {
+			o = strings.TrimSpace(o)
+			if o == "" {
+				return nil, NewCommandError(ErrCodeInvalidPayload, "option %d is empty", i+1)
+			}
+
+			options = append(options, o)
+		}
+
+		round.Type = RoundChoice
+		round.Options = options
+	}
+
+	err = c.hub.store.UpdateRound(*round)
+	if err != nil {
+		return nil, err
+	}
+
+	c.hub.BroadcastRounds(round.GameID)
+
+	return round, nil
+}
+
+// Choose records the option a player picked in a multiple choice round.
+// Nobody but the moderator learns what was picked until the round is
+// revealed.
+func (c *Connection) Choose(msg SocketMessage) error {
+	var payload ChoicePayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return InvalidPayload(err)
+	}
+
+	player, err := c.GetPlayer()
+	if err != nil {
+		return err
+	}
+
+	round, err := c.GetActiveRound()
+	if err != nil {
+		return err
+	}
+
+	if !round.IsChoice() {
+		return ErrWrongRoundType
+	}
+
+	err = round.AcceptsAnswers(c.hub.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	if payload.Option < 0 || payload.Option >= len(round.Options) {
+		return NewCommandError(ErrCodeInvalidPayload, "option must be between 0 and %d", len(round.Options)-1)
+	}
+
+	teamId, err := c.hub.answerTeam(*round, player.ID)
+	if err != nil {
+		return err
+	}
+
+	choice := payload.Option
+
+	answer, err := c.hub.store.FindAnswerByPlayer(round.GameID, round.ID, player.ID)
+	if err == nil {
+		answer.Choice = &choice
+		answer.TeamID = teamId
+		err = c.hub.store.UpdateAnswer(*answer)
+	} else {
+		err = c.hub.store.CreateAnswer(Answer{
+			ID:       uuid.New().String(),
+			GameID:   round.GameID,
+			PlayerID: player.ID,
+			RoundID:  round.ID,
+			Choice:   &choice,
+			TeamID:   teamId,
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.hub.BroadcastAnswers(round.GameID)
+	c.hub.SendChoiceResultsToModerator(*round)
+
+	return nil
+}
+
+func (h *Hub) ChoiceResultsMessage(round GameRound) (*Message, error) {
+	answers, err := h.store.FindAllAnswersByGameAndRound(round.GameID, round.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []ChoiceResult{}
+	for i, o := range round.Options {
+		results = append(results, ChoiceResult{
+			Option:  i,
+			Text:    o,
+			Players: []string{},
+		})
+	}
+
+	for _, a := range answers {
+		if a.Choice == nil || *a.Choice >= len(results) {
+			continue
+		}
+
+		results[*a.Choice].Count++
+		results[*a.Choice].Players = append(results[*a.Choice].Players, a.PlayerID)
+	}
+
+	return NewMessage("choice_results", ChoiceResultsPayload{
+		RoundID: round.ID,
+		Results: results,
+	})
+}
+
+// BroadcastChoiceResults sends the tallies of a revealed round to the game.
+func (h *Hub) BroadcastChoiceResults(round GameRound) {
+	msg, err := h.ChoiceResultsMessage(round)
+	if err != nil {
+		log.Println("build choice_results:", err)
+		return
+	}
+
+	h.Broadcast(round.GameID, msg)
+}
+
+// SendChoiceResultsToModerator keeps the tallies up to date in every
+// connection of the moderator while the round is still hidden from the
+// players.
+func (h *Hub) SendChoiceResultsToModerator(round GameRound) {
+	room, ok := h.rooms[round.GameID]
+	if !ok {
+		return
+	}
+
+	game, err := h.store.FindGameById(round.GameID)
+	if err != nil {
+		log.Println("find game:", err)
+		return
+	}
+
+	msg, err := h.ChoiceResultsMessage(round)
+	if err != nil {
+		log.Println("build choice_results:", err)
+		return
+	}
+
+	for conn := range room.Members {
+		if conn.PlayerID == nil || *conn.PlayerID != game.ModeratorUUID {
+			continue
+		}
+
+		err = conn.Send(msg)
+		if err != nil {
+			log.Println("write:", err)
+		}
+	}
+}
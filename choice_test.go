@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+// Every tab of the moderator gets the live tallies, and no player does.
+func TestChoiceResultsReachEveryModeratorTab(t *testing.T) {
+	hub := newTestHub(t)
+
+	mod := connect(t, hub, nil)
+	defer mod.close()
+	modID := mod.mustHello("Mod")
+
+	var game Game
+	mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &game)
+
+	tab := connect(t, hub, &Session{PlayerID: modID})
+	defer tab.close()
+	tab.mustRequest("subscribe", GameIDPayload{GameID: game.ID}, nil)
+
+	player := connect(t, hub, nil)
+	defer player.close()
+	player.mustHello("Player")
+	player.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+
+	options := map[string]any{"gameId": game.ID, "options": []string{"Red", "Blue"}}
+	mod.mustRequest("set_choice_options", options, nil)
+	mod.mustRequest("start_round", GameIDPayload{GameID: game.ID}, nil)
+	player.mustRequest("choose", map[string]any{"gameId": game.ID, "option": 1}, nil)
+
+	for name, c := range map[string]*testClient{"first tab": mod, "second tab": tab} {
+		// The moderator's own requests come after what was queued before.
+		c.mustRequest("get_text", GameIDPayload{GameID: game.ID}, nil)
+
+		if _, ok := c.last("choice_results"); !ok {
+			t.Errorf("the moderator's %s got no tallies", name)
+		}
+	}
+
+	if _, ok := player.last("choice_results"); ok {
+		t.Fatal("a player got the tallies of a hidden round")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a Clock that only moves when the test advances it.
+type manualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	clock *manualClock
+	at    time.Time
+	f     func()
+	done  bool
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *manualClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &manualTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+
+	return t
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	stopped := !t.done
+	t.done = true
+
+	return stopped
+}
+
+// Set moves the clock to now without firing any timers.
+func (c *manualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// Advance moves the clock on by d and fires the timers due by then, in the
+// order they are due. Callbacks run on the caller's goroutine.
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+
+	due := []*manualTimer{}
+	for _, t := range c.timers {
+		if !t.done && !t.at.After(c.now) {
+			t.done = true
+			due = append(due, t)
+		}
+	}
+
+	c.timers = slices.DeleteFunc(c.timers, func(t *manualTimer) bool { return t.done })
+	c.mu.Unlock()
+
+	slices.SortStableFunc(due, func(a, b *manualTimer) int { return a.at.Compare(b.at) })
+
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// useManualClock makes the hub run on a manual clock.
+func useManualClock(hub *Hub) *manualClock {
+	clock := newManualClock()
+
+	hub.Do(func() {
+		hub.clock = clock
+	})
+
+	return clock
+}
+
+// advance moves the clock on by d and waits for the hub to handle what the
+// timers that fired queued.
+func advance(hub *Hub, clock *manualClock, d time.Duration) {
+	clock.Advance(d)
+	hub.Do(func() {})
+}
+
+func TestManualClockFiresTimersInOrder(t *testing.T) {
+	clock := newManualClock()
+	fired := []string{}
+
+	clock.AfterFunc(2*time.Second, func() { fired = append(fired, "b") })
+	clock.AfterFunc(time.Second, func() { fired = append(fired, "a") })
+	stopped := clock.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	clock.AfterFunc(3*time.Second, func() { fired = append(fired, "c") })
+
+	if !stopped.Stop() || stopped.Stop() {
+		t.Fatal("Stop should only report true the first time")
+	}
+
+	clock.Advance(2 * time.Second)
+
+	if !slices.Equal(fired, []string{"a", "b"}) {
+		t.Fatalf("fired %v, want [a b]", fired)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Store         string
+	MongoURI      string
+	MongoDatabase string
+
+	// SendQueueSize bounds the number of messages queued per connection.
+	SendQueueSize int
+	// QueuePolicy decides what happens when that queue is full.
+	QueuePolicy QueuePolicy
+	// WriteWait is the time allowed to write a single message to a client.
+	WriteWait time.Duration
+	// PingInterval is how often clients are pinged. Clients that answer
+	// neither a ping nor send anything else within PongWait are dropped.
+	PingInterval time.Duration
+	PongWait     time.Duration
+
+	// SessionSecret signs new session tokens. SessionPreviousSecrets are
+	// still accepted when verifying, so the secret can be rotated.
+	SessionSecret          string
+	SessionPreviousSecrets []string
+	SessionTTL             time.Duration
+
+	// RoundTickInterval is how often timed rounds broadcast the time left.
+	RoundTickInterval time.Duration
+
+	// Defaults for judging answers against accepted answers: the edit
+	// distances still judged correct and close, and the points for each.
+	MatchTolerance      int
+	MatchCloseTolerance int
+	CorrectPoints       int
+	ClosePoints         int
+
+	// Defaults for voting on answers.
+	VotesPerPlayer int
+	PointsPerVote  int
+
+	// PresenceGracePeriod is how long disconnected players are away before
+	// they count as gone.
+	PresenceGracePeriod time.Duration
+
+	// ReplayBufferSize is the number of events kept per game for clients
+	// that resume after a reconnect.
+	ReplayBufferSize int
+}
+
+// LoadConfig reads the server configuration from the environment.
+func LoadConfig() (Config, error) {
+	config := Config{
+		Store:         getEnv("STORE", "memory"),
+		MongoURI:      getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		MongoDatabase: getEnv("MONGO_DATABASE", "league-game"),
+	}
+
+	var err error
+
+	config.SendQueueSize, err = getEnvInt("SEND_QUEUE_SIZE", 64)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if config.SendQueueSize < 1 {
+		return Config{}, fmt.Errorf("SEND_QUEUE_SIZE must be at least 1")
+	}
+
+	config.QueuePolicy, err = ParseQueuePolicy(getEnv("SEND_QUEUE_POLICY", string(QueueCoalesce)))
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.WriteWait, err = getEnvDuration("WRITE_WAIT", 10*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.PingInterval, err = getEnvDuration("PING_INTERVAL", 30*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.PongWait, err = getEnvDuration("PONG_WAIT", 60*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if config.PingInterval <= 0 || config.PingInterval >= config.PongWait {
+		return Config{}, fmt.Errorf("PING_INTERVAL must be positive and shorter than PONG_WAIT")
+	}
+
+	config.SessionSecret = getEnv("SESSION_SECRET", "")
+
+	for _, s := range strings.Split(getEnv("SESSION_PREVIOUS_SECRETS", ""), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			config.SessionPreviousSecrets = append(config.SessionPreviousSecrets, s)
+		}
+	}
+
+	config.SessionTTL, err = getEnvDuration("SESSION_TTL", 30*24*time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.RoundTickInterval, err = getEnvDuration("ROUND_TICK_INTERVAL", 5*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if config.RoundTickInterval <= 0 {
+		return Config{}, fmt.Errorf("ROUND_TICK_INTERVAL must be positive")
+	}
+
+	config.MatchTolerance, err = getEnvInt("MATCH_TOLERANCE", 1)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.MatchCloseTolerance, err = getEnvInt("MATCH_CLOSE_TOLERANCE", 3)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.CorrectPoints, err = getEnvInt("CORRECT_POINTS", 1)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.ClosePoints, err = getEnvInt("CLOSE_POINTS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.VotesPerPlayer, err = getEnvInt("VOTES_PER_PLAYER", 1)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.PointsPerVote, err = getEnvInt("POINTS_PER_VOTE", 1)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.ReplayBufferSize, err = getEnvInt("REPLAY_BUFFER_SIZE", 256)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if config.ReplayBufferSize < 0 {
+		return Config{}, fmt.Errorf("REPLAY_BUFFER_SIZE must not be negative")
+	}
+
+	config.PresenceGracePeriod, err = getEnvDuration("PRESENCE_GRACE_PERIOD", 30*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+func getEnv(key string, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) (int, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return n, nil
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestLoadConfigReplayBufferSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"", 256, false},
+		{"0", 0, false},
+		{"16", 16, false},
+		{"-1", 0, true},
+		{"many", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("REPLAY_BUFFER_SIZE", tt.value)
+
+			config, err := LoadConfig()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %d, want an error", config.ReplayBufferSize)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if config.ReplayBufferSize != tt.want {
+				t.Fatalf("got %d, want %d", config.ReplayBufferSize, tt.want)
+			}
+		})
+	}
+}
@@ -1,96 +1,80 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/gorilla/websocket"
 )
 
 type Connection struct {
-	Conn     *websocket.Conn
+	Conn     Socket
 	PlayerID *string
-}
-
-func (c *Connection) GetPlayer() (*Player, error) {
-	if c.PlayerID == nil {
-		return nil, fmt.Errorf("player id is nil")
-	}
+	hub      *Hub
 
-	for _, p := range players {
-		if p.ID == *c.PlayerID {
-			return p, nil
-		}
-	}
+	// session is the verified session the player ID was taken from.
+	session *Session
 
-	return nil, fmt.Errorf("player not found")
-}
+	// version is the protocol version the client speaks, taken from the
+	// messages it sends.
+	version int
 
-func (c *Connection) Remove() {
-	player, err := c.GetPlayer()
+	send   chan outboundMessage
+	sendMu sync.Mutex
+	closed bool
 
-	if err != nil {
-		log.Println("get player:", err)
-		return
-	}
+	rooms map[string]*Room
 
-	p, err := json.Marshal(player)
+	// game is the game the command being handled targets, if it targets
+	// one. It is only set while the command runs, see Handle.
+	game *Game
+}
 
-	if err != nil {
-		log.Println("marshal:", err)
-		return
+func NewConnection(hub *Hub, conn Socket, session *Session) *Connection {
+	c := &Connection{
+		Conn:    conn,
+		hub:     hub,
+		session: session,
+		send:    make(chan outboundMessage, hub.config.SendQueueSize),
+		rooms:   map[string]*Room{},
 	}
 
-	msg := SocketMessage{
-		Type:    "player_disconnected",
-		Payload: string(p),
+	if session != nil {
+		c.PlayerID = &session.PlayerID
 	}
 
-	data, err := json.Marshal(msg)
+	return c
+}
 
-	if err != nil {
-		log.Println("marshal:", err)
+func (c *Connection) GetPlayer() (*Player, error) {
+	if c.PlayerID == nil {
+		return nil, ErrNotIdentified
 	}
 
-	for i, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
-
-		if conn.PlayerID == c.PlayerID {
-			connections = append(connections[:i], connections[i+1:]...)
-			continue
-		}
-
-		err := conn.Conn.WriteMessage(websocket.TextMessage, []byte(data))
-
-		if err != nil {
-			log.Println("write:", err)
-		}
-	}
+	return c.hub.store.FindPlayerById(*c.PlayerID)
 }
 
-func (c *Connection) SendJoinSuccess(game Game) error {
-	data, err := json.Marshal(game)
+// Send encodes msg for the client's protocol version and queues it.
+func (c *Connection) Send(msg *Message) error {
+	data, err := msg.Encode(c.version)
 	if err != nil {
-		log.Println("marshal:", err)
 		return err
 	}
 
-	answer := SocketMessage{
-		Type:    "join_game",
-		Payload: string(data),
-	}
+	return c.Write(msg.Type, msg.GameID, data)
+}
 
-	data, err = json.Marshal(answer)
+func (c *Connection) sendPayload(kind string, payload any) error {
+	msg, err := NewMessage(kind, payload)
 	if err != nil {
 		log.Println("marshal:", err)
 		return err
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(data))
+	err = c.Send(msg)
 	if err != nil {
 		log.Println("write:", err)
 		return err
@@ -99,179 +83,186 @@ func (c *Connection) SendJoinSuccess(game Game) error {
 	return nil
 }
 
-func (c *Connection) SendPlayerConnectedToAll(game Game, player Player) error {
-	data, err := json.Marshal(player)
-	if err != nil {
-		log.Println("marshal:", err)
-		return err
-	}
-
-	res := SocketMessage{
-		Type:    "player_connected",
-		Payload: string(data),
-	}
-
-	data, err = json.Marshal(res)
-	if err != nil {
-		log.Println("marshal:", err)
-		return err
+// Remove marks the player away once their last connection is gone.
+func (c *Connection) Remove() {
+	if c.PlayerID == nil {
+		return
 	}
 
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
-
-		player, err := conn.GetPlayer()
-		if err != nil {
-			log.Println("get player:", err)
-			continue
-		}
-
-		found := false
-
-		for _, p := range game.Players {
-			if p != player.ID {
-				continue
-			}
-
-			found = true
+	for conn := range c.hub.connections {
+		if conn.PlayerID != nil && *conn.PlayerID == *c.PlayerID {
+			return
 		}
+	}
 
-		if !found {
-			continue
-		}
+	player, err := c.GetPlayer()
 
-		err = conn.Conn.WriteMessage(websocket.TextMessage, []byte(data))
-		if err != nil {
-			log.Println("write:", err)
-		}
+	if err != nil {
+		log.Println("get player:", err)
+		return
 	}
 
-	return nil
+	c.hub.PlayerAway(*player)
 }
 
-func (c *Connection) HandleGameNotFound() error {
-	answer := SocketMessage{
-		Type:    "join_game",
-		Payload: "false",
-	}
+func (c *Connection) SendJoinSuccess(game Game) error {
+	return c.sendPayload("join_game", game)
+}
 
-	data, err := json.Marshal(answer)
+func (c *Connection) SendPlayerConnectedToAll(game Game, player Player) error {
+	msg, err := NewMessage("player_connected", player)
 	if err != nil {
 		log.Println("marshal:", err)
 		return err
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(data))
-	if err != nil {
-		log.Println("write:", err)
-		return err
-	}
+	c.hub.Broadcast(game.ID, msg)
 
 	return nil
 }
 
-func (c *Connection) JoinGame(msg SocketMessage) {
-	player, err := c.GetPlayer()
-	if err != nil {
-		log.Println("get player:", err)
+func (c *Connection) HandleGameNotFound() error {
+	return c.sendPayload("join_game", "false")
+}
+
+// SendAck confirms that the command in msg succeeded. Legacy clients that
+// did not ask for a reply get none.
+func (c *Connection) SendAck(msg SocketMessage, result any) {
+	if msg.Version == 0 && msg.RequestID == "" {
 		return
 	}
 
-	game, err := FindGameById(msg.Payload)
-
+	reply, err := NewMessage("ack", AckPayload{
+		Command: msg.Type,
+		Result:  result,
+	})
 	if err != nil {
-		log.Println("find one:", err)
+		log.Println("marshal:", err)
 		return
 	}
 
-	players := append(game.Players, *c.PlayerID)
+	reply.RequestID = msg.RequestID
 
-	err = UpdateGamePlayers(game.ID, players)
+	err = c.Send(reply)
 	if err != nil {
-		log.Println("update game players:", err)
-		return
+		log.Println("write:", err)
 	}
+}
 
-	err = c.SendJoinSuccess(*game)
-	if err != nil {
-		log.Println("send join success:", err)
+// SendError tells the client why the command in msg failed.
+func (c *Connection) SendError(msg SocketMessage, err error) {
+	reply, marshalErr := NewMessage("error", NewErrorPayload(msg.Type, err))
+	if marshalErr != nil {
+		log.Println("marshal:", marshalErr)
 		return
 	}
 
-	err = c.SendPlayerConnectedToAll(*game, *player)
-	if err != nil {
-		log.Println("send player connected to all:", err)
-		return
-	}
+	reply.RequestID = msg.RequestID
 
-	c.SendAllAnswers()
+	writeErr := c.Send(reply)
+	if writeErr != nil {
+		log.Println("write:", writeErr)
+	}
 }
 
-func (c *Connection) SendAllAnswers() {
-	game, err := c.GetActiveGame()
+func (c *Connection) JoinGame(msg SocketMessage) (*Game, error) {
+	var payload JoinGamePayload
+	err := msg.Decode(&payload)
 	if err != nil {
-		log.Println("get active game:", err)
-		return
+		return nil, InvalidPayload(err)
 	}
 
-	round, err := c.GetActiveRound()
+	player, err := c.GetPlayer()
 	if err != nil {
-		log.Println("get active round:", err)
-		return
+		return nil, err
 	}
 
-	answers, err := FindAllAnswersByGameAndRound(game.ID, round.ID)
-	if err != nil {
-		log.Println("find all answers by game and round:", err)
-		return
+	var game *Game
+
+	if payload.Code != "" {
+		game, err = c.hub.store.FindGameByCode(NormalizeJoinCode(payload.Code))
+	} else {
+		game, err = c.hub.store.FindGameById(payload.GameID)
+
+		// Private games can only be found through their join code.
+		if err == nil && !game.VisibleTo(player.ID) {
+			game, err = nil, ErrGameNotFound
+		}
 	}
 
-	data, err := json.Marshal(answers)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		if c.version == 0 {
+			c.HandleGameNotFound()
+		}
+
+		return nil, err
 	}
 
-	response := SocketMessage{
-		Type:    "all_answers",
-		Payload: string(data),
+	if RoleOf(*game, player.ID) == RoleNone {
+		err = game.CheckPassword(payload.Password, msg.passwordHash)
+		if err != nil {
+			return nil, err
+		}
+
+		players := append(game.Players, player.ID)
+
+		err = c.hub.store.UpdateGamePlayers(game.ID, players)
+		if err != nil {
+			return nil, err
+		}
+
+		game.Players = players
 	}
 
-	responseData, err := json.Marshal(response)
+	c.hub.JoinRoom(c, *game)
+	c.game = game
 
+	err = c.SendJoinSuccess(*game)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		return nil, err
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(responseData))
-
+	err = c.SendPlayerConnectedToAll(*game, *player)
 	if err != nil {
-		log.Println("write:", err)
-		return
+		return nil, err
 	}
+
+	c.SendAllAnswers()
+
+	return game, nil
 }
 
-func (c *Connection) UnhandledMessage(msg SocketMessage) {
-	answer := SocketMessage{
-		Type:    "error",
-		Payload: "unknown message type",
+func (c *Connection) SendAllAnswers() error {
+	game, err := c.GetActiveGame()
+	if err != nil {
+		log.Println("get active game:", err)
+		return err
 	}
 
-	data, err := json.Marshal(answer)
+	// Spectators without an identity see what nobody in the game would.
+	viewerId := ""
+	if c.PlayerID != nil {
+		viewerId = *c.PlayerID
+	}
 
+	msg, err := c.hub.AnswersMessage(game.ID, viewerId)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		log.Println("build all_answers:", err)
+		return err
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(data))
+	err = c.Send(msg)
 
 	if err != nil {
 		log.Println("write:", err)
+		return err
 	}
+
+	return nil
+}
+
+func (c *Connection) UnhandledMessage(msg SocketMessage) error {
+	return NewCommandError(ErrCodeUnknownCommand, "unknown message type %q", msg.Type)
 }
 
 func (c *Connection) GetActiveRound() (*GameRound, error) {
@@ -282,7 +273,11 @@ func (c *Connection) GetActiveRound() (*GameRound, error) {
 		return nil, err
 	}
 
-	round, err := FindActiveRoundByGameId(game.ID)
+	round, err := c.hub.store.FindActiveRoundByGameId(game.ID)
+
+	if errors.Is(err, ErrRoundNotFound) {
+		return nil, ErrNoActiveRound
+	}
 
 	if err != nil {
 		log.Println("find one:", err)
@@ -292,7 +287,15 @@ func (c *Connection) GetActiveRound() (*GameRound, error) {
 	return round, nil
 }
 
+// GetActiveGame returns the game the current command targets. Commands
+// that do not name one fall back to the only game the player moderates or
+// plays in, which fails for players in several games.
 func (c *Connection) GetActiveGame() (*Game, error) {
+	if c.game != nil {
+		game := *c.game
+		return &game, nil
+	}
+
 	player, err := c.GetPlayer()
 
 	if err != nil {
@@ -300,385 +303,347 @@ func (c *Connection) GetActiveGame() (*Game, error) {
 		return nil, err
 	}
 
-	playerGames := []Game{}
-	for _, g := range games {
-		for _, p := range g.Players {
-			if p == player.ID {
-				playerGames = append(playerGames, *g)
-			}
-		}
+	games, err := c.hub.playerGames(player.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(playerGames) == 0 {
-		moderatorGames := []Game{}
-		for _, g := range games {
-			if g.ModeratorUUID == player.ID {
-				moderatorGames = append(moderatorGames, *g)
-			}
-		}
-
-		if len(moderatorGames) == 0 {
-			return nil, fmt.Errorf("no active games")
-		}
-
-		if len(moderatorGames) > 1 {
-			return nil, fmt.Errorf("multiple active games")
-		}
-
-		return &moderatorGames[0], nil
+	if len(games) == 0 {
+		return nil, ErrNoActiveGame
 	}
 
-	if len(playerGames) > 1 {
-		return nil, fmt.Errorf("multiple active games")
+	if len(games) > 1 {
+		return nil, ErrMultipleActiveGames
 	}
 
-	return &playerGames[0], nil
+	return &games[0], nil
 }
 
-func (c *Connection) SendConnectedPlayers() {
+func (c *Connection) SendConnectedPlayers() error {
 	game, err := c.GetActiveGame()
 	if err != nil {
 		log.Println("SEND_CONNECTED_PLAYERS: get active game:", err)
-		return
-	}
-
-	playerIds := []string{}
-
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
-
-		g, err := conn.GetActiveGame()
-
-		if err != nil {
-			log.Println("get active game:", err)
-			continue
-		}
-
-		if game.ID != g.ID {
-			continue
-		}
-
-		playerIds = append(playerIds, *conn.PlayerID)
-	}
-
-	selectedPlayers := []Player{}
-
-	for _, p := range players {
-		for _, id := range playerIds {
-			if p.ID == id {
-				selectedPlayers = append(selectedPlayers, *p)
-			}
-		}
-	}
-
-	payload, err := json.Marshal(selectedPlayers)
-
-	if err != nil {
-		log.Println("marshal:", err)
-		return
-	}
-
-	answer := SocketMessage{
-		Type:    "get_connected_players",
-		Payload: string(payload),
+		return err
 	}
 
-	data, err := json.Marshal(answer)
-
+	msg, err := c.hub.ConnectedPlayersMessage(game.ID)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		log.Println("build get_connected_players:", err)
+		return err
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(data))
+	err = c.Send(msg)
 
 	if err != nil {
 		log.Println("write:", err)
+		return err
 	}
+
+	return nil
 }
 
+// SayHelloPayload is sent by clients to identify themselves. Older clients
+// also send their player ID as "uuid"; it is public and therefore ignored.
+// Clients that reconnect send the game and sequence number of the last
+// event they saw to only get what they missed, see replay.go.
 type SayHelloPayload struct {
-	Name string `json:"name"`
-	UUID string `json:"uuid"`
+	Name    string `json:"name"`
+	Token   string `json:"token"`
+	GameID  string `json:"gameId"`
+	LastSeq uint64 `json:"lastSeq"`
 }
 
-func (c *Connection) SayHello(msg SocketMessage) {
+// SayHello identifies the player behind the connection. Players prove who
+// they are with the session token they were issued; anybody without a valid
+// one becomes a new player and gets a token for next time.
+func (c *Connection) SayHello(msg SocketMessage) (*Player, error) {
 	var payload SayHelloPayload
-	err := json.Unmarshal([]byte(msg.Payload), &payload)
+	err := msg.Decode(&payload)
 	if err != nil {
-		log.Println("unmarshal:", err)
-		return
+		return nil, InvalidPayload(err)
+	}
+
+	fmt.Println("Player connected:", payload.Name)
+
+	session := c.session
+
+	if payload.Token != "" {
+		verified, err := c.hub.sessions.Verify(payload.Token)
+		if err != nil {
+			log.Println("verify session:", err)
+		} else {
+			session = verified
+		}
 	}
 
-	fmt.Println("Player connected:", payload)
+	var player *Player
 
-	if payload.UUID == "" {
-		fmt.Println("player uuid is empty", payload.UUID)
+	if session != nil {
+		player, err = c.hub.store.FindPlayerById(session.PlayerID)
+		if err != nil && !errors.Is(err, ErrPlayerNotFound) {
+			return nil, err
+		}
+	}
 
-		newPlayer := Player{
+	if player == nil {
+		player = &Player{
 			Nickname: payload.Name,
 			ID:       uuid.New().String(),
 		}
 
-		players = append(players, &newPlayer)
-		c.PlayerID = &newPlayer.ID
+		// Sessions issued when the socket was opened name a player that
+		// does not exist yet.
+		if session != nil {
+			player.ID = session.PlayerID
+		}
 
-		c.SendPlayerConnected(newPlayer)
-		c.SendSetUuid()
-		c.SendCurrentGame()
-		c.SendAllAnswers()
-		c.SendAllGames()
-		c.SendAllRounds()
-		c.SendConnectedPlayers()
-		c.SendCurrentText()
+		err = c.hub.store.CreatePlayer(*player)
+		if err != nil {
+			return nil, err
+		}
 
-		return
-	}
+		c.PlayerID = &player.ID
 
-	var player Player
-	found := false
+		err = c.IssueSession()
+		if err != nil {
+			return nil, err
+		}
 
-	for _, p := range players {
-		if p.ID == payload.UUID {
-			player = *p
-			found = true
+		c.SendSetUuid()
+	} else {
+		player.Nickname = payload.Name
 
-			p.Nickname = payload.Name
-			c.PlayerID = &payload.UUID
+		err = c.hub.store.UpdatePlayer(*player)
+		if err != nil {
+			return nil, err
+		}
 
-			break
+		c.PlayerID = &player.ID
+		c.session = session
+
+		if c.hub.sessions.NeedsRotation(*session) {
+			err = c.IssueSession()
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	if !found {
-		player = Player{
-			Nickname: payload.Name,
-			ID:       uuid.New().String(),
-		}
+	c.hub.PlayerOnline(*player)
+	games := c.JoinGameRooms()
 
-		c.PlayerID = &player.ID
-		players = append(players, &player)
+	c.SendPlayerConnected(*player)
 
-		c.SendSetUuid()
+	// Clients that resume, and players in several games, get a snapshot of
+	// each game instead of the messages below, which only cover one.
+	if payload.LastSeq > 0 || len(games) > 1 {
+		c.SendAllGames()
+
+		for _, game := range games {
+			if game.ID == payload.GameID && c.Resume(game, payload.LastSeq) {
+				continue
+			}
+
+			c.SendSnapshot(game)
+		}
+
+		return player, nil
 	}
 
-	c.SendPlayerConnected(player)
 	c.SendCurrentGame()
 	c.SendAllAnswers()
 	c.SendAllGames()
 	c.SendAllRounds()
 	c.SendConnectedPlayers()
 	c.SendCurrentText()
+
+	return player, nil
 }
 
-func (c *Connection) SendSetUuid() {
-	answer := SocketMessage{
-		Type:    "set_uuid",
-		Payload: *c.PlayerID,
+// IssueSession creates a new session token for the connection's player and
+// sends it to the client, which should present it on its next hello.
+func (c *Connection) IssueSession() error {
+	token, session, err := c.hub.sessions.Issue(*c.PlayerID)
+	if err != nil {
+		return err
 	}
 
-	data, err := json.Marshal(answer)
+	c.session = &session
+
+	return c.sendPayload("set_session", SessionPayload{
+		Token:     token,
+		PlayerID:  session.PlayerID,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
 
+// JoinGameRooms subscribes the connection to the rooms of all games its
+// player plays or moderates, and returns those games. The games learn that
+// the player is connected through their presence.
+func (c *Connection) JoinGameRooms() []Game {
+	games, err := c.hub.playerGames(*c.PlayerID)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		log.Println("find player games:", err)
+		return nil
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(data))
-
-	if err != nil {
-		log.Println("write:", err)
+	for _, game := range games {
+		c.hub.JoinRoom(c, game)
+		c.hub.BroadcastPresence(game.ID)
 	}
+
+	return games
+}
+
+func (c *Connection) SendSetUuid() error {
+	return c.sendPayload("set_uuid", *c.PlayerID)
 }
 
+// SendPlayerConnected confirms the player to their own connection. The
+// games they are part of learn about them through their presence.
 func (c *Connection) SendPlayerConnected(player Player) {
-	data, err := json.Marshal(player)
-	if err != nil {
-		return
-	}
+	c.sendPayload("player_connected", player)
+}
 
-	msg := SocketMessage{
-		Type:    "player_connected",
-		Payload: string(data),
+func (c *Connection) SetAnswer(msg SocketMessage) error {
+	var payload TextPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return InvalidPayload(err)
 	}
 
-	data, err = json.Marshal(msg)
+	player, err := c.GetPlayer()
 	if err != nil {
-		return
+		return err
 	}
 
-	for _, conn := range connections {
-		if c.PlayerID == nil {
-			continue
-		}
-
-		if c.PlayerID == &player.ID {
-			continue
-		}
+	round, err := c.GetActiveRound()
 
-		err := conn.Conn.WriteMessage(websocket.TextMessage, []byte(data))
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			fmt.Println("write:", err)
-		}
+	if round.IsChoice() {
+		return ErrWrongRoundType
 	}
-}
 
-func (c *Connection) SetAnswer(msg SocketMessage) {
-	player, err := c.GetPlayer()
+	err = round.AcceptsAnswers(c.hub.clock.Now())
 	if err != nil {
-		log.Println("get player:", err)
-		return
+		return err
 	}
 
-	round, err := c.GetActiveRound()
-
+	teamId, err := c.hub.answerTeam(*round, player.ID)
 	if err != nil {
-		log.Println("get active round:", err)
-		return
+		return err
 	}
 
-	found := false
-	for _, a := range answers {
-		if a.GameID == round.GameID && a.PlayerID == player.ID && round.ID == a.RoundID {
-			found = true
-			a.Text = msg.Payload
-			break
-		}
-	}
+	answer, err := c.hub.store.FindAnswerByPlayer(round.GameID, round.ID, player.ID)
 
-	if !found {
-		newAnswer := Answer{
+	if err == nil {
+		answer.Text = payload.Text
+		answer.TeamID = teamId
+		err = c.hub.store.UpdateAnswer(*answer)
+	} else {
+		err = c.hub.store.CreateAnswer(Answer{
+			ID:       uuid.New().String(),
 			GameID:   round.GameID,
 			PlayerID: player.ID,
 			RoundID:  round.ID,
-			Text:     msg.Payload,
-		}
-
-		answers = append(answers, &newAnswer)
-
-		for _, conn := range connections {
-			if conn.PlayerID == nil {
-				continue
-			}
-
-			conn.SendAllAnswers()
-		}
+			Text:     payload.Text,
+			TeamID:   teamId,
+		})
+	}
 
-		return
+	if err != nil {
+		return err
 	}
 
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
+	c.hub.BroadcastAnswers(round.GameID)
 
-		conn.SendAllAnswers()
-	}
+	return nil
 }
 
-func (c *Connection) SetText(msg SocketMessage) {
-	round, err := c.GetActiveRound()
-
+func (c *Connection) SetText(msg SocketMessage) error {
+	var payload TextPayload
+	err := msg.Decode(&payload)
 	if err != nil {
-		log.Println("get active round:", err)
-		return
+		return InvalidPayload(err)
 	}
 
-	for _, r := range rounds {
-		if r.ID == round.ID {
-			r.Question = msg.Payload
-		}
+	round, err := c.GetActiveRound()
+
+	if err != nil {
+		return err
 	}
 
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
+	round.Question = payload.Text
 
-		conn.SendCurrentText()
+	err = c.hub.store.UpdateRound(*round)
+	if err != nil {
+		return err
 	}
-}
 
-func (c *Connection) SendCurrentText() {
-	if c.PlayerID == nil {
-		return
-	}
+	c.hub.BroadcastText(round.GameID)
 
-	round, err := c.GetActiveRound()
+	return nil
+}
 
+func (c *Connection) SendCurrentText() error {
+	game, err := c.GetActiveGame()
 	if err != nil {
-		log.Println("get active round:", err)
-		return
-	}
-
-	msg := SocketMessage{
-		Type:    "set_text",
-		Payload: round.Question,
+		log.Println("get active game:", err)
+		return err
 	}
 
-	data, err := json.Marshal(msg)
-
+	msg, err := c.hub.TextMessage(game.ID)
 	if err != nil {
-		return
+		log.Println("build set_text:", err)
+		return err
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(data))
+	err = c.Send(msg)
 	if err != nil {
 		fmt.Printf("Failed to write message: %s\n", err)
+		return err
 	}
+
+	return nil
 }
 
-func (c *Connection) LeaveGame(msg SocketMessage) {
+func (c *Connection) LeaveGame(msg SocketMessage) error {
+	var payload GameIDPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return InvalidPayload(err)
+	}
+
 	player, err := c.GetPlayer()
 
 	if err != nil {
-		log.Println("get player:", err)
-		return
+		return err
 	}
 
-	for _, g := range games {
-		if g.ID == msg.Payload {
-			for i, p := range g.Players {
-				if p == player.ID {
-					g.Players = append(g.Players[:i], g.Players[i+1:]...)
-					break
-				}
-			}
+	game, err := c.hub.store.FindGameById(payload.GameID)
+	if err == nil {
+		err = c.hub.RemovePlayer(game, player.ID)
+		if err != nil {
+			return err
 		}
 	}
 
-	answer := SocketMessage{
-		Type:    "leave_game",
-		Payload: player.ID,
-	}
-
-	data, err := json.Marshal(answer)
+	answer, err := NewMessage("leave_game", player.ID)
 
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		return err
 	}
 
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
-
-		if conn.PlayerID == c.PlayerID {
-			continue
-		}
-
-		err := conn.Conn.WriteMessage(websocket.TextMessage, []byte(data))
-
-		if err != nil {
-			log.Println("write:", err)
-		}
-	}
+	c.hub.LeaveRoom(c, payload.GameID)
+	c.hub.SyncRooms(player.ID)
+	c.hub.Broadcast(payload.GameID, answer)
 
 	c.SendAllGames()
+
+	return nil
 }
 
 type SetCanTypePayload struct {
@@ -693,439 +658,694 @@ type AnswerPayload struct {
 	RoundID string `json:"roundId"`
 }
 
-func ChangeAnswerVisibility(msg SocketMessage, visible bool) {
-	for _, a := range answers {
-		if a.ID == msg.Payload {
-			a.RevealedToPlayers = visible
-			break
-		}
+func (c *Connection) ChangeAnswerVisibility(msg SocketMessage, visible bool) error {
+	var payload AnswerIDPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return InvalidPayload(err)
 	}
 
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
+	answer, err := c.hub.store.FindAnswerById(payload.AnswerID)
+	if err != nil {
+		return err
+	}
+
+	answer.RevealedToPlayers = visible
 
-		conn.SendAllAnswers()
+	err = c.hub.store.UpdateAnswer(*answer)
+	if err != nil {
+		return err
 	}
+
+	c.hub.BroadcastAnswers(answer.GameID)
+	c.hub.RefreshBallot(answer.RoundID)
+
+	return nil
 }
 
-func (c *Connection) HideAnswer(msg SocketMessage) {
-	ChangeAnswerVisibility(msg, false)
+func (c *Connection) HideAnswer(msg SocketMessage) error {
+	return c.ChangeAnswerVisibility(msg, false)
 }
 
-func (c *Connection) RevealAnswer(msg SocketMessage) {
-	ChangeAnswerVisibility(msg, true)
+func (c *Connection) RevealAnswer(msg SocketMessage) error {
+	return c.ChangeAnswerVisibility(msg, true)
 }
 
-func (c *Connection) CreateGame(msg SocketMessage) {
+func (c *Connection) CreateGame(msg SocketMessage) (*Game, error) {
+	var payload CreateGamePayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
 	player, err := c.GetPlayer()
 
 	if err != nil {
-		log.Println("CREATE_GAME: get player:", err)
-		return
+		return nil, err
+	}
+
+	code, err := NewJoinCode(c.hub.store)
+	if err != nil {
+		return nil, err
 	}
 
 	game := Game{
-		Name:          msg.Payload,
+		Name:          payload.Name,
+		Code:          code,
 		Players:       []string{},
 		ModeratorUUID: player.ID,
 		ID:            uuid.New().String(),
+		Private:       payload.Private,
+	}
+
+	if payload.Password != "" {
+		if msg.passwordHash == "" {
+			return nil, fmt.Errorf("password was not hashed")
+		}
+
+		game.SetPasswordHash(msg.passwordHash)
 	}
 
 	round := GameRound{
 		ID:       uuid.New().String(),
 		GameID:   game.ID,
-		Active:   true,
+		State:    RoundDraft,
 		Round:    1,
 		Answers:  []Answer{},
 		Question: "",
 	}
 
-	games = append(games, &game)
-	rounds = append(rounds, &round)
+	err = c.hub.store.CreateGame(game)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, g := range games {
-		if g.ID != game.ID {
-			for i, p := range g.Players {
-				if p == player.ID {
-					g.Players = append(g.Players[:i], g.Players[i+1:]...)
-					break
-				}
-			}
-		}
+	err = c.hub.store.CreateRound(round)
+	if err != nil {
+		return nil, err
 	}
 
-	c.SendAllGames()
+	c.hub.JoinRoom(c, game)
+	c.game = &game
+
 	c.SendAllRounds()
 	c.SendCurrentGame()
 	c.SendAllAnswers()
 	c.SendCurrentText()
 	c.SendConnectedPlayers()
 
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
+	c.hub.BroadcastGames()
 
-		if conn.PlayerID == c.PlayerID {
-			continue
-		}
+	return &game, nil
+}
 
-		conn.SendAllGames()
-		conn.SendAllRounds()
-		conn.SendCurrentGame()
-		conn.SendAllAnswers()
-		conn.SendCurrentText()
-		conn.SendConnectedPlayers()
+// SetGamePrivacy changes whether a game is listed publicly and which
+// password, if any, players need to join it.
+func (c *Connection) SetGamePrivacy(msg SocketMessage) (*Game, error) {
+	var payload SetGamePrivacyPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
 	}
-}
 
-func (c *Connection) SendAllGames() {
-	data, err := json.Marshal(games)
+	game, err := c.hub.store.FindGameById(payload.GameID)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		return nil, err
 	}
 
-	response := SocketMessage{
-		Type:    "get_games",
-		Payload: string(data),
+	game.Private = payload.Private
+
+	if payload.Password != nil {
+		if *payload.Password != "" && msg.passwordHash == "" {
+			return nil, fmt.Errorf("password was not hashed")
+		}
+
+		game.SetPasswordHash(msg.passwordHash)
+	}
+
+	err = c.hub.store.UpdateGame(*game)
+	if err != nil {
+		return nil, err
 	}
 
-	responseData, err := json.Marshal(response)
+	c.hub.BroadcastGames()
+
+	return game, nil
+}
+
+func (c *Connection) SendAllGames() error {
+	if c.PlayerID == nil {
+		return ErrNotIdentified
+	}
 
+	msg, err := c.hub.GamesMessage(*c.PlayerID)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		log.Println("build get_games:", err)
+		return err
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(responseData))
+	err = c.Send(msg)
 
 	if err != nil {
 		log.Println("write:", err)
-		return
+		return err
 	}
+
+	return nil
 }
 
-func (c *Connection) SendAllRounds() {
+func (c *Connection) SendAllRounds() error {
 	game, err := c.GetActiveGame()
 
 	if err != nil {
 		log.Println("get active game:", err)
-		return
+		return err
 	}
 
-	responseRounds := []GameRound{}
+	msg, err := c.hub.RoundsMessage(game.ID)
 
-	for _, r := range rounds {
-		if r.GameID == game.ID {
-			responseRounds = append(responseRounds, *r)
-		}
+	if err != nil {
+		log.Println("build get_rounds:", err)
+		return err
 	}
 
-	data, err := json.Marshal(responseRounds)
+	err = c.Send(msg)
 
 	if err != nil {
-		log.Println("marshal:", err)
-		return
-	}
-
-	answer := SocketMessage{
-		Type:    "get_rounds",
-		Payload: string(data),
+		log.Println("write:", err)
+		return err
 	}
 
-	responseData, err := json.Marshal(answer)
+	return nil
+}
 
+func (c *Connection) GoNextRound(msg SocketMessage) error {
+	var payload GameIDPayload
+	err := msg.Decode(&payload)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		return InvalidPayload(err)
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(responseData))
-
+	game, err := c.hub.store.FindGameById(payload.GameID)
 	if err != nil {
-		log.Println("write:", err)
-		return
+		return err
 	}
-}
-
-type JoinGamePayload struct {
-	GameID string `json:"gameId"`
-}
 
-func (c *Connection) GoNextRound(msg SocketMessage) {
-	player, err := c.GetPlayer()
+	round, err := c.hub.store.FindActiveRoundByGameId(game.ID)
+	if errors.Is(err, ErrRoundNotFound) {
+		return ErrNoActiveRound
+	}
 
 	if err != nil {
-		return
+		return err
 	}
 
-	var payload JoinGamePayload
-	err = json.Unmarshal([]byte(msg.Payload), &payload)
+	err = round.Transition(RoundArchived)
 	if err != nil {
-		return
+		return err
 	}
 
-	found := false
-	nextRound := 1
-
-	for _, g := range games {
-		if g.ID == payload.GameID && g.ModeratorUUID == player.ID {
-			for _, r := range rounds {
-				if r.Active {
-					r.Active = false
-					r.Ended = true
-					r.Started = false
-					nextRound = r.Round + 1
-					found = true
-					break
-				}
-			}
-		}
-	}
+	c.hub.StopRoundTimer(game.ID)
+	c.hub.CloseBuzzer(game.ID)
 
-	if !found {
-		fmt.Println("Game or round not found")
-		return
+	nextRound := round.Round + 1
+
+	err = c.hub.store.UpdateRound(*round)
+	if err != nil {
+		return err
 	}
 
 	newRound := GameRound{
 		GameID:   payload.GameID,
-		Active:   true,
+		State:    RoundDraft,
 		Question: "",
 		Answers:  []Answer{},
 		Round:    nextRound,
-		Started:  false,
-		Ended:    false,
 		ID:       uuid.New().String(),
 	}
 
-	rounds = append(rounds, &newRound)
-
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
-
-		g, err := conn.GetActiveGame()
+	err = c.hub.LoadQuestion(game, &newRound)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			log.Println("get active game:", err)
-			continue
-		}
+	err = c.hub.store.CreateRound(newRound)
+	if err != nil {
+		return err
+	}
 
-		if g.ID != payload.GameID {
-			continue
-		}
+	c.hub.BroadcastRounds(game.ID)
+	c.hub.BroadcastConnectedPlayers(game.ID)
+	c.hub.BroadcastAnswers(game.ID)
+	c.hub.BroadcastText(game.ID)
 
-		conn.SendAllRounds()
-		conn.SendAllGames()
-		conn.SendConnectedPlayers()
-		conn.SendAllAnswers()
-		conn.SendCurrentText()
+	if room, ok := c.hub.rooms[game.ID]; ok {
+		c.hub.broadcastGames(room.Members)
 	}
+
+	return nil
 }
 
-func (c *Connection) SendCurrentGame() {
+func (c *Connection) SendCurrentGame() error {
 	game, err := c.GetActiveGame()
 
 	if err != nil {
 		log.Println("get active game:", err)
-		return
+		return err
 	}
 
-	data, err := json.Marshal(game)
+	return c.sendPayload("get_game", game)
+}
 
+func (c *Connection) StartRound(msg SocketMessage) error {
+	var payload StartRoundPayload
+	err := msg.Decode(&payload)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		return InvalidPayload(err)
 	}
 
-	answer := SocketMessage{
-		Type:    "get_game",
-		Payload: string(data),
+	if payload.Duration < 0 {
+		return NewCommandError(ErrCodeInvalidPayload, "duration must not be negative")
 	}
 
-	responseData, err := json.Marshal(answer)
+	round, err := c.GetActiveRound()
+	if err != nil {
+		return err
+	}
 
+	err = round.Transition(RoundOpen)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		return err
 	}
 
-	err = c.Conn.WriteMessage(websocket.TextMessage, []byte(responseData))
+	round.Deadline = nil
+
+	if payload.Duration > 0 {
+		deadline := c.hub.clock.Now().Add(time.Duration(payload.Duration) * time.Second)
+		round.Deadline = &deadline
+	}
 
+	err = c.hub.store.UpdateRound(*round)
 	if err != nil {
-		log.Println("write:", err)
-		return
+		return err
 	}
+
+	c.hub.BroadcastRounds(round.GameID)
+	c.hub.StartRoundTimer(*round)
+
+	return nil
 }
 
-func (c *Connection) StartRound() {
+func (c *Connection) EndRound() error {
 	round, err := c.GetActiveRound()
 	if err != nil {
-		log.Println("get active round:", err)
-		return
+		return err
 	}
 
-	for _, r := range rounds {
-		if r.ID == round.ID {
-			r.Started = true
-			r.Ended = false
-		}
+	return c.hub.EndRound(*round)
+}
+
+// EndRound closes round for answers. Moderators do this by hand, timed
+// rounds also when their deadline passes.
+func (h *Hub) EndRound(round GameRound) error {
+	err := round.Transition(RoundClosed)
+	if err != nil {
+		return err
 	}
 
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
+	h.StopRoundTimer(round.GameID)
+	h.CloseBuzzer(round.GameID)
 
-		conn.SendAllRounds()
+	err = h.store.UpdateRound(round)
+	if err != nil {
+		return err
 	}
+
+	h.BroadcastRounds(round.GameID)
+
+	return h.JudgeAnswers(round)
 }
 
-func (c *Connection) EndRound() {
+// SetAnswerPoints awards points for an answer. It works for answers of past
+// rounds too, so the moderator can correct earlier scores.
+func (c *Connection) SetAnswerPoints(msg SocketMessage) (*Answer, error) {
+	var payload AnswerPointsPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	answer, err := c.hub.store.FindAnswerById(payload.AnswerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Points != nil {
+		answer.Points = *payload.Points
+	} else {
+		answer.Points += payload.Delta
+	}
+
+	answer.PointsOverridden = true
+
+	err = c.hub.store.UpdateAnswer(*answer)
+	if err != nil {
+		return nil, err
+	}
+
+	c.hub.BroadcastAnswers(answer.GameID)
+	c.hub.BroadcastLeaderboard(answer.GameID)
+
+	return answer, nil
+}
+
+func (c *Connection) SendLeaderboard() error {
+	game, err := c.GetActiveGame()
+	if err != nil {
+		return err
+	}
+
+	msg, err := c.hub.LeaderboardMessage(game.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.Send(msg)
+}
+
+// RevealRound shows every answer of the closed active round to the players.
+func (c *Connection) RevealRound() error {
 	round, err := c.GetActiveRound()
 	if err != nil {
-		log.Println("get active round:", err)
-		return
+		return err
 	}
 
-	for _, r := range rounds {
-		if r.ID == round.ID {
-			r.Ended = true
-			r.Started = false
-		}
+	err = round.Transition(RoundRevealed)
+	if err != nil {
+		return err
 	}
 
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
+	answers, err := c.hub.store.FindAllAnswersByGameAndRound(round.GameID, round.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, answer := range answers {
+		if answer.RevealedToPlayers {
 			continue
 		}
 
-		conn.SendAllRounds()
+		answer.RevealedToPlayers = true
+
+		err = c.hub.store.UpdateAnswer(answer)
+		if err != nil {
+			return err
+		}
 	}
+
+	err = c.hub.store.UpdateRound(*round)
+	if err != nil {
+		return err
+	}
+
+	c.hub.BroadcastRounds(round.GameID)
+	c.hub.BroadcastAnswers(round.GameID)
+
+	if round.Voting != nil && round.Voting.Open {
+		c.hub.BroadcastBallot(*round)
+	}
+
+	if round.IsChoice() {
+		c.hub.BroadcastChoiceResults(*round)
+	}
+
+	return nil
 }
 
-func (c *Connection) DeleteAnswer(msg SocketMessage) {
-	for i, a := range answers {
-		if a.ID == msg.Payload {
-			answers = append(answers[:i], answers[i+1:]...)
-			break
-		}
+func (c *Connection) DeleteAnswer(msg SocketMessage) error {
+	var payload AnswerIDPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return InvalidPayload(err)
 	}
 
-	for _, conn := range connections {
-		if conn.PlayerID == nil {
-			continue
-		}
+	answer, err := c.hub.store.FindAnswerById(payload.AnswerID)
+	if err != nil {
+		return err
+	}
+
+	err = c.hub.store.DeleteAnswer(answer.ID)
+	if err != nil {
+		return err
+	}
+
+	c.hub.BroadcastAnswers(answer.GameID)
+	c.hub.RefreshBallot(answer.RoundID)
 
-		conn.SendAllAnswers()
+	if answer.Score() != 0 {
+		c.hub.BroadcastLeaderboard(answer.GameID)
 	}
+
+	return nil
 }
 
-func (c *Connection) DeleteGame(msg SocketMessage) {
-	player, err := c.GetPlayer()
+func (c *Connection) DeleteGame(msg SocketMessage) error {
+	var payload GameIDPayload
+	err := msg.Decode(&payload)
 	if err != nil {
-		log.Println("get player:", err)
-		return
+		return InvalidPayload(err)
 	}
 
-	for i, g := range games {
-		if g.ID == msg.Payload && g.ModeratorUUID == player.ID {
-			games = append(games[:i], games[i+1:]...)
-			break
-		}
+	game, err := c.hub.store.FindGameById(payload.GameID)
+	if err != nil {
+		return err
 	}
 
-	for i, a := range answers {
-		if a.GameID == msg.Payload {
-			answers = append(answers[:i], answers[i+1:]...)
-			break
-		}
+	err = c.hub.store.DeleteGame(game.ID)
+	if err != nil {
+		return err
 	}
 
-	msg = SocketMessage{
-		Type:    "game_deleted",
-		Payload: msg.Payload,
+	err = c.hub.store.DeleteRoundsByGameId(game.ID)
+	if err != nil {
+		log.Println("delete rounds:", err)
 	}
 
-	data, err := json.Marshal(msg)
+	err = c.hub.store.DeleteAnswersByGameId(game.ID)
 	if err != nil {
-		log.Println("marshal:", err)
-		return
+		log.Println("delete answers:", err)
 	}
 
-	for _, conn := range connections {
+	c.hub.StopRoundTimer(game.ID)
+	delete(c.hub.buzzers, game.ID)
+
+	deleted, err := NewMessage("game_deleted", game.ID)
+	if err != nil {
+		return err
+	}
+
+	// Spectators without an identity are not reached by the loop below.
+	if room, ok := c.hub.rooms[game.ID]; ok {
+		for conn := range room.Spectators {
+			if conn.PlayerID == nil {
+				conn.Send(deleted)
+			}
+		}
+	}
+
+	c.hub.CloseRoom(game.ID)
+
+	for conn := range c.hub.connections {
 		if conn.PlayerID == nil {
 			continue
 		}
 
-		err = conn.Conn.WriteMessage(websocket.TextMessage, []byte(data))
+		err = conn.Send(deleted)
 		if err != nil {
 			log.Println("write:", err)
-			continue
 		}
-
-		conn.SendAllGames()
 	}
+
+	c.hub.BroadcastGames()
+
+	return nil
 }
 
+// Listen reads client messages until the client goes away or misses its
+// heartbeats, then unregisters the connection.
 func (c *Connection) Listen() {
+	c.extendReadDeadline()
+	c.Conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
-			c.Remove()
 			break
 		}
 
+		c.extendReadDeadline()
+
 		msg, err := ParseSocketMessage(message)
 
 		if err != nil {
 			log.Println("parse:", err)
-			c.Remove()
-			break
+			c.hub.Reject(c, msg, InvalidPayload(err))
+			continue
 		}
 
-		switch msg.Type {
-		case "create_game":
-			c.CreateGame(msg)
-		case "leave_game":
-			c.LeaveGame(msg)
-		case "get_text":
-			c.SendCurrentText()
-		case "join_game":
-			c.JoinGame(msg)
-		case "set_answer":
-			c.SetAnswer(msg)
-		case "set_text":
-			c.SetText(msg)
-		case "set_answer_visible":
-			c.RevealAnswer(msg)
-		case "set_answer_invisible":
-			c.HideAnswer(msg)
-		case "get_connected_players":
-			c.SendConnectedPlayers()
-		case "end_round":
-			c.EndRound()
-		case "start_round":
-			c.StartRound()
-		case "get_rounds":
-			c.SendAllRounds()
-		case "delete_answer":
-			c.DeleteAnswer(msg)
-		case "delete_game":
-			c.DeleteGame(msg)
-		case "go_next_round":
-			c.GoNextRound(msg)
-		case "get_game":
-			c.SendCurrentGame()
-		case "say_hello":
-			c.SayHello(msg)
-		default:
-			c.UnhandledMessage(msg)
-		}
+		c.hub.Dispatch(c, c.hub.preparePassword(msg))
 	}
+
+	c.hub.Unregister(c)
+}
+
+// Handle runs a single client message and replies with an ack or an error.
+// It must only be called from the hub goroutine.
+func (c *Connection) Handle(msg SocketMessage) {
+	result, err := c.Execute(msg)
+	if err != nil {
+		log.Printf("%s: %s", msg.Type, err)
+		c.SendError(msg, err)
+		return
+	}
+
+	c.SendAck(msg, result)
+}
+
+// Execute runs a single client message and returns the result it is
+// acknowledged with. It must only be called from the hub goroutine.
+func (c *Connection) Execute(msg SocketMessage) (any, error) {
+	if msg.Version > ProtocolVersion {
+		return nil, NewCommandError(ErrCodeUnsupportedVersion, "unsupported protocol version %d", msg.Version)
+	}
+
+	if msg.Version > c.version {
+		c.version = msg.Version
+	}
+
+	var result any
+
+	game, err := c.Authorize(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.game = game
+	defer func() {
+		c.game = nil
+	}()
+
+	switch msg.Type {
+	case "create_game":
+		result, err = c.CreateGame(msg)
+	case "leave_game":
+		err = c.LeaveGame(msg)
+	case "get_text":
+		err = c.SendCurrentText()
+	case "join_game":
+		result, err = c.JoinGame(msg)
+	case "subscribe":
+		result, err = c.Subscribe()
+	case "unsubscribe":
+		err = c.Unsubscribe(msg)
+	case "spectate":
+		result, err = c.Spectate(msg)
+	case "stop_spectating":
+		err = c.StopSpectating(msg)
+	case "set_answer":
+		err = c.SetAnswer(msg)
+	case "choose":
+		err = c.Choose(msg)
+	case "start_voting":
+		result, err = c.StartVoting(msg)
+	case "vote":
+		err = c.Vote(msg)
+	case "end_voting":
+		err = c.EndVoting()
+	case "open_buzzer":
+		result, err = c.OpenBuzzer(msg)
+	case "buzz":
+		err = c.Buzz()
+	case "accept_buzz":
+		err = c.AcceptBuzz(msg)
+	case "reject_buzz":
+		err = c.RejectBuzz(msg)
+	case "reset_buzzer":
+		err = c.ResetBuzzer()
+	case "create_team":
+		result, err = c.CreateTeam(msg)
+	case "delete_team":
+		result, err = c.DeleteTeam(msg)
+	case "assign_player":
+		result, err = c.AssignPlayer(msg)
+	case "set_team_captain":
+		result, err = c.SetTeamCaptain(msg)
+	case "shuffle_teams":
+		result, err = c.ShuffleTeams(msg)
+	case "set_answer_mode":
+		result, err = c.SetAnswerMode(msg)
+	case "get_team_leaderboard":
+		err = c.SendTeamLeaderboard()
+	case "set_choice_options":
+		result, err = c.SetChoiceOptions(msg)
+	case "set_text":
+		err = c.SetText(msg)
+	case "set_answer_visible":
+		err = c.RevealAnswer(msg)
+	case "set_answer_invisible":
+		err = c.HideAnswer(msg)
+	case "get_connected_players":
+		err = c.SendConnectedPlayers()
+	case "end_round":
+		err = c.EndRound()
+	case "start_round":
+		err = c.StartRound(msg)
+	case "reveal_round":
+		err = c.RevealRound()
+	case "get_rounds":
+		err = c.SendAllRounds()
+	case "delete_answer":
+		err = c.DeleteAnswer(msg)
+	case "set_answer_points":
+		result, err = c.SetAnswerPoints(msg)
+	case "set_answer_verdict":
+		result, err = c.SetAnswerVerdict(msg)
+	case "set_accepted_answers":
+		result, err = c.SetAnswerKey(msg)
+	case "get_leaderboard":
+		err = c.SendLeaderboard()
+	case "get_presence":
+		err = c.SendPresence()
+	case "set_presence_policy":
+		result, err = c.SetPresencePolicy(msg)
+	case "delete_game":
+		err = c.DeleteGame(msg)
+	case "set_game_privacy":
+		result, err = c.SetGamePrivacy(msg)
+	case "set_anonymous_answers":
+		result, err = c.SetAnonymousAnswers(msg)
+	case "attach_question_set":
+		result, err = c.AttachQuestionSet(msg)
+	case "go_next_round":
+		err = c.GoNextRound(msg)
+	case "get_game":
+		err = c.SendCurrentGame()
+	case "get_games":
+		err = c.SendAllGames()
+	case "get_answers":
+		err = c.SendAllAnswers()
+	case "say_hello":
+		result, err = c.SayHello(msg)
+	default:
+		err = c.UnhandledMessage(msg)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
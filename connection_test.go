@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+func TestMalformedEnvelopeGetsError(t *testing.T) {
+	hub := newTestHub(t)
+	c := connect(t, hub, nil)
+	defer c.close()
+
+	messages := []string{
+		`{"v":"1","type":"get_games","requestId":"bad-version"}`,
+		`{"type":`,
+	}
+
+	for _, m := range messages {
+		c.sendRaw([]byte(m))
+	}
+
+	errors := 0
+	_, ok := c.waitFor(func(m SocketMessage) bool {
+		if m.Type != "error" {
+			return false
+		}
+
+		var payload ErrorPayload
+		if m.Decode(&payload) != nil || payload.Code != ErrCodeInvalidPayload {
+			t.Fatalf("unexpected error reply %s", m.Payload)
+		}
+
+		errors++
+		return errors == len(messages)
+	})
+	if !ok {
+		t.Fatalf("got %d error replies, want %d", errors, len(messages))
+	}
+
+	if reply := c.messages("error")[0]; reply.RequestID != "bad-version" {
+		t.Fatalf("error reply %s lost the request ID", reply.Payload)
+	}
+
+	// The connection is still usable.
+	c.mustHello("still here")
+}
+
+// Commands without a game fall back to the one game the player takes part
+// in, whether they play or moderate it.
+func TestGetActiveGame(t *testing.T) {
+	tests := []struct {
+		name  string
+		games []Game
+		want  error
+	}{
+		{"none", nil, ErrNoActiveGame},
+		{"plays one", []Game{{ID: "g1", ModeratorUUID: "mod", Players: []string{"p"}}}, nil},
+		{"moderates one", []Game{{ID: "g1", ModeratorUUID: "p"}}, nil},
+		{"moderates and plays it", []Game{{ID: "g1", ModeratorUUID: "p", Players: []string{"p"}}}, nil},
+		{"plays one, moderates another", []Game{
+			{ID: "g1", ModeratorUUID: "mod", Players: []string{"p"}},
+			{ID: "g2", ModeratorUUID: "p"},
+		}, ErrMultipleActiveGames},
+		{"plays two", []Game{
+			{ID: "g1", ModeratorUUID: "mod", Players: []string{"p"}},
+			{ID: "g2", ModeratorUUID: "mod", Players: []string{"p"}},
+		}, ErrMultipleActiveGames},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := newTestHub(t)
+			c := NewConnection(hub, nil, &Session{PlayerID: "p"})
+
+			var game *Game
+			var err error
+
+			hub.Do(func() {
+				hub.store.CreatePlayer(Player{ID: "p", Nickname: "p"})
+				for _, g := range tt.games {
+					hub.store.CreateGame(g)
+				}
+
+				game, err = c.GetActiveGame()
+			})
+
+			if err != tt.want {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+
+			if tt.want == nil && game.ID != "g1" {
+				t.Fatalf("got game %s, want g1", game.ID)
+			}
+		})
+	}
+}
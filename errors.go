@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorCode is a machine readable reason sent to clients in error replies.
+type ErrorCode string
+
+const (
+	ErrCodeInvalidPayload      ErrorCode = "INVALID_PAYLOAD"
+	ErrCodeUnknownCommand      ErrorCode = "UNKNOWN_COMMAND"
+	ErrCodeUnsupportedVersion  ErrorCode = "UNSUPPORTED_VERSION"
+	ErrCodeNotIdentified       ErrorCode = "NOT_IDENTIFIED"
+	ErrCodePlayerNotFound      ErrorCode = "PLAYER_NOT_FOUND"
+	ErrCodeGameNotFound        ErrorCode = "GAME_NOT_FOUND"
+	ErrCodeNoActiveGame        ErrorCode = "NO_ACTIVE_GAME"
+	ErrCodeMultipleActiveGames ErrorCode = "MULTIPLE_ACTIVE_GAMES"
+	ErrCodeRoundNotFound       ErrorCode = "ROUND_NOT_FOUND"
+	ErrCodeNoActiveRound       ErrorCode = "NO_ACTIVE_ROUND"
+	ErrCodeRoundNotStarted     ErrorCode = "ROUND_NOT_STARTED"
+	ErrCodeRoundClosed         ErrorCode = "ROUND_CLOSED"
+	ErrCodeInvalidRoundState   ErrorCode = "INVALID_ROUND_STATE"
+	ErrCodeQuestionSetNotFound ErrorCode = "QUESTION_SET_NOT_FOUND"
+	ErrCodeWrongRoundType      ErrorCode = "WRONG_ROUND_TYPE"
+	ErrCodeVotingClosed        ErrorCode = "VOTING_CLOSED"
+	ErrCodeSelfVote            ErrorCode = "SELF_VOTE"
+	ErrCodeNoVotesLeft         ErrorCode = "NO_VOTES_LEFT"
+	ErrCodeAlreadyVoted        ErrorCode = "ALREADY_VOTED"
+	ErrCodeBuzzerClosed        ErrorCode = "BUZZER_CLOSED"
+	ErrCodeAlreadyBuzzed       ErrorCode = "ALREADY_BUZZED"
+	ErrCodeBuzzNotFound        ErrorCode = "BUZZ_NOT_FOUND"
+	ErrCodeTeamNotFound        ErrorCode = "TEAM_NOT_FOUND"
+	ErrCodeNotCaptain          ErrorCode = "NOT_CAPTAIN"
+	ErrCodeAnswerNotFound      ErrorCode = "ANSWER_NOT_FOUND"
+	ErrCodeNotModerator        ErrorCode = "NOT_MODERATOR"
+	ErrCodeForbidden           ErrorCode = "FORBIDDEN"
+	ErrCodeSessionInvalid      ErrorCode = "SESSION_INVALID"
+	ErrCodeSessionExpired      ErrorCode = "SESSION_EXPIRED"
+	ErrCodePasswordRequired    ErrorCode = "PASSWORD_REQUIRED"
+	ErrCodeWrongPassword       ErrorCode = "WRONG_PASSWORD"
+	ErrCodeInternal            ErrorCode = "INTERNAL_ERROR"
+)
+
+var (
+	ErrNotIdentified       = NewCommandError(ErrCodeNotIdentified, "player id is nil")
+	ErrNoActiveGame        = NewCommandError(ErrCodeNoActiveGame, "no active games")
+	ErrMultipleActiveGames = NewCommandError(ErrCodeMultipleActiveGames, "multiple active games, name the game with gameId")
+	ErrNoActiveRound       = NewCommandError(ErrCodeNoActiveRound, "no active round")
+	ErrRoundNotStarted     = NewCommandError(ErrCodeRoundNotStarted, "round not started")
+	ErrNotModerator        = NewCommandError(ErrCodeNotModerator, "not the moderator")
+)
+
+// CommandError is an error that is reported back to the client that sent
+// the failing command.
+type CommandError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+func NewCommandError(code ErrorCode, format string, args ...any) *CommandError {
+	return &CommandError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// InvalidPayload reports a payload that could not be decoded.
+func InvalidPayload(err error) *CommandError {
+	return NewCommandError(ErrCodeInvalidPayload, "invalid payload: %s", err)
+}
+
+// HTTPStatus maps an error code to the status REST handlers respond with.
+func HTTPStatus(code ErrorCode) int {
+	switch code {
+	case ErrCodeInvalidPayload, ErrCodeUnknownCommand, ErrCodeUnsupportedVersion:
+		return http.StatusBadRequest
+	case ErrCodeNotIdentified, ErrCodeSessionInvalid, ErrCodeSessionExpired:
+		return http.StatusUnauthorized
+	case ErrCodeNotModerator, ErrCodeForbidden, ErrCodePasswordRequired, ErrCodeWrongPassword, ErrCodeNotCaptain:
+		return http.StatusForbidden
+	case ErrCodeGameNotFound, ErrCodeRoundNotFound, ErrCodePlayerNotFound, ErrCodeAnswerNotFound, ErrCodeQuestionSetNotFound, ErrCodeTeamNotFound:
+		return http.StatusNotFound
+	case ErrCodeInternal:
+		return http.StatusInternalServerError
+	}
+
+	return http.StatusConflict
+}
+
+// NewErrorPayload describes err for the client. Errors that are not a
+// CommandError are reported as internal errors without their details, which
+// only end up in the server log.
+func NewErrorPayload(command string, err error) ErrorPayload {
+	var commandErr *CommandError
+	if errors.As(err, &commandErr) {
+		return ErrorPayload{
+			Command: command,
+			Code:    commandErr.Code,
+			Message: commandErr.Message,
+		}
+	}
+
+	return ErrorPayload{
+		Command: command,
+		Code:    ErrCodeInternal,
+		Message: "internal error",
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+// Hub owns every connection and all game state. Connections never touch
+// shared state themselves: registration, unregistration and every incoming
+// message are queued as commands and run one after another on the hub
+// goroutine, so handlers can use the store and the connection set without
+// further locking.
+type Hub struct {
+	config      Config
+	store       GameStore
+	sessions    *SessionManager
+	connections map[*Connection]bool
+	rooms       map[string]*Room
+	commands    chan func()
+	clock       Clock
+	// timers holds the running round timer of each game by game ID.
+	timers map[string]*roundTimer
+	// buzzers holds the buzzer of each game by game ID.
+	buzzers map[string]*Buzzer
+	// presence tracks every player seen since the hub started by player ID.
+	presence map[string]*playerPresence
+}
+
+func NewHub(config Config, store GameStore, sessions *SessionManager) *Hub {
+	return &Hub{
+		config:      config,
+		store:       store,
+		sessions:    sessions,
+		connections: map[*Connection]bool{},
+		rooms:       map[string]*Room{},
+		commands:    make(chan func(), 256),
+		clock:       realClock{},
+		timers:      map[string]*roundTimer{},
+		buzzers:     map[string]*Buzzer{},
+		presence:    map[string]*playerPresence{},
+	}
+}
+
+// Run processes queued commands until the process exits.
+func (h *Hub) Run() {
+	for command := range h.commands {
+		command()
+	}
+}
+
+// Do runs fn on the hub goroutine and waits for it to finish.
+func (h *Hub) Do(fn func()) {
+	done := make(chan struct{})
+
+	h.commands <- func() {
+		defer close(done)
+		fn()
+	}
+
+	<-done
+}
+
+func (h *Hub) Register(c *Connection) {
+	h.commands <- func() {
+		h.connections[c] = true
+	}
+}
+
+func (h *Hub) Unregister(c *Connection) {
+	h.commands <- func() {
+		if !h.connections[c] {
+			return
+		}
+
+		delete(h.connections, c)
+		h.LeaveAllRooms(c)
+		c.Close()
+		c.Remove()
+	}
+}
+
+func (h *Hub) Dispatch(c *Connection, msg SocketMessage) {
+	h.commands <- func() {
+		if !h.connections[c] {
+			return
+		}
+
+		c.Handle(msg)
+	}
+}
+
+// Reject answers a message that could not be handled with err.
+func (h *Hub) Reject(c *Connection, msg SocketMessage, err error) {
+	h.commands <- func() {
+		if !h.connections[c] {
+			return
+		}
+
+		c.SendError(msg, err)
+	}
+}
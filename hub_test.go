@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestHub starts a hub with an in-memory store and the default config.
+func newTestHub(t testing.TB) *Hub {
+	t.Helper()
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := NewSessionManager([]string{"test-secret"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := NewHub(config, NewMemoryStore(), sessions)
+	go hub.Run()
+
+	return hub
+}
+
+// testClient is a client connected to the hub through a fakeSocket, set up
+// the way HandleWebsocket sets up real ones.
+type testClient struct {
+	t        testing.TB
+	hub      *Hub
+	socket   *fakeSocket
+	conn     *Connection
+	mu       sync.Mutex
+	requests int
+	// received holds the messages the client got, decoded.
+	received []SocketMessage
+}
+
+func connect(t testing.TB, hub *Hub, session *Session) *testClient {
+	socket := newFakeSocket()
+	conn := NewConnection(hub, socket, session)
+
+	go conn.writePump()
+	hub.Register(conn)
+	go conn.Listen()
+
+	return &testClient{t: t, hub: hub, socket: socket, conn: conn}
+}
+
+// replyError is the error reply to a request.
+type replyError ErrorPayload
+
+func (e *replyError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Command, e.Code, e.Message)
+}
+
+// sendRaw delivers data to the server as it is.
+func (c *testClient) sendRaw(data []byte) {
+	c.socket.deliver(data)
+}
+
+// send sends a command and returns its request ID.
+func (c *testClient) send(kind string, payload any) string {
+	c.mu.Lock()
+	c.requests++
+	requestId := fmt.Sprintf("r%d", c.requests)
+	c.mu.Unlock()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+
+	msg, err := json.Marshal(SocketMessage{
+		Version:   ProtocolVersion,
+		Type:      kind,
+		RequestID: requestId,
+		Payload:   data,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	c.sendRaw(msg)
+
+	return requestId
+}
+
+// receive decodes the messages written since the last call and returns
+// all messages the client got so far.
+func (c *testClient) receive() []SocketMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, data := range c.socket.frames(len(c.received)) {
+		var msg SocketMessage
+		if json.Unmarshal(data, &msg) != nil {
+			msg = SocketMessage{Type: "undecodable", Payload: data}
+		}
+
+		c.received = append(c.received, msg)
+	}
+
+	return c.received
+}
+
+// messages returns every message of the given type the client got so far.
+func (c *testClient) messages(kind string) []SocketMessage {
+	res := []SocketMessage{}
+
+	for _, msg := range c.receive() {
+		if msg.Type == kind {
+			res = append(res, msg)
+		}
+	}
+
+	return res
+}
+
+// last returns the most recent message of the given type.
+func (c *testClient) last(kind string) (SocketMessage, bool) {
+	msgs := c.messages(kind)
+	if len(msgs) == 0 {
+		return SocketMessage{}, false
+	}
+
+	return msgs[len(msgs)-1], true
+}
+
+// waitFor waits until the client got a message match accepts.
+func (c *testClient) waitFor(match func(SocketMessage) bool) (SocketMessage, bool) {
+	timeout := time.After(60 * time.Second)
+
+	seen := 0
+
+	for {
+		received := c.receive()
+
+		for _, msg := range received[seen:] {
+			if match(msg) {
+				return msg, true
+			}
+		}
+
+		seen = len(received)
+
+		select {
+		case <-c.socket.written:
+		case <-timeout:
+			return SocketMessage{}, false
+		}
+	}
+}
+
+// request sends a command and waits for its reply. It returns the result
+// of an ack, or the error of an error reply as a *replyError.
+func (c *testClient) request(kind string, payload any) (json.RawMessage, error) {
+	requestId := c.send(kind, payload)
+
+	reply, ok := c.waitFor(func(m SocketMessage) bool {
+		return m.RequestID == requestId && (m.Type == "ack" || m.Type == "error")
+	})
+	if !ok {
+		return nil, fmt.Errorf("%s: no reply", kind)
+	}
+
+	if reply.Type == "error" {
+		var e replyError
+		err := json.Unmarshal(reply.Payload, &e)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, &e
+	}
+
+	var ack struct {
+		Result json.RawMessage `json:"result"`
+	}
+
+	err := json.Unmarshal(reply.Payload, &ack)
+	if err != nil {
+		return nil, err
+	}
+
+	return ack.Result, nil
+}
+
+// mustRequest sends a command that has to succeed and decodes its result
+// into result, unless that is nil. It must only be called from the test's
+// goroutine.
+func (c *testClient) mustRequest(kind string, payload any, result any) {
+	c.t.Helper()
+
+	data, err := c.request(kind, payload)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if result != nil {
+		err = json.Unmarshal(data, result)
+		if err != nil {
+			c.t.Fatalf("%s: decode result: %s", kind, err)
+		}
+	}
+}
+
+// hello identifies the client as a new player and returns their ID.
+func (c *testClient) hello(name string) (string, error) {
+	data, err := c.request("say_hello", SayHelloPayload{Name: name})
+	if err != nil {
+		return "", err
+	}
+
+	var player Player
+	err = json.Unmarshal(data, &player)
+
+	return player.ID, err
+}
+
+func (c *testClient) mustHello(name string) string {
+	c.t.Helper()
+
+	id, err := c.hello(name)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	return id
+}
+
+// close disconnects the client.
+func (c *testClient) close() {
+	c.socket.Close()
+}
+
+// waitForConnections waits until the hub has n connections registered.
+func waitForConnections(t testing.TB, hub *Hub, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+
+	for {
+		var registered int
+		hub.Do(func() {
+			registered = len(hub.connections)
+		})
+
+		if registered == n {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("hub has %d connections, want %d", registered, n)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestHubConcurrentClients has hundreds of clients join games, answer and
+// leave again all at once. It is meant to be run with -race.
+func TestHubConcurrentClients(t *testing.T) {
+	const games = 10
+	const playersPerGame = 20
+
+	hub := newTestHub(t)
+	gameIds := []string{}
+
+	for i := range games {
+		mod := connect(t, hub, nil)
+		mod.mustHello(fmt.Sprintf("moderator %d", i))
+
+		var game Game
+		mod.mustRequest("create_game", CreateGamePayload{Name: fmt.Sprintf("game %d", i)}, &game)
+		mod.mustRequest("start_round", map[string]any{"gameId": game.ID}, nil)
+
+		gameIds = append(gameIds, game.ID)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := range games * playersPerGame {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			gameID := gameIds[i%games]
+			c := connect(t, hub, nil)
+			defer c.close()
+
+			steps := []struct {
+				kind    string
+				payload any
+			}{
+				{"join_game", JoinGamePayload{GameID: gameID}},
+				{"set_answer", map[string]any{"gameId": gameID, "text": fmt.Sprintf("answer %d", i)}},
+				{"get_answers", GameIDPayload{GameID: gameID}},
+				{"leave_game", GameIDPayload{GameID: gameID}},
+			}
+
+			_, err := c.hello(fmt.Sprintf("player %d", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			for _, step := range steps {
+				_, err = c.request(step.kind, step.payload)
+				if err != nil {
+					t.Errorf("player %d: %s", i, err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	waitForConnections(t, hub, games)
+
+	for _, id := range gameIds {
+		var game *Game
+		var answers []Answer
+		var members int
+		var err error
+
+		hub.Do(func() {
+			game, err = hub.store.FindGameById(id)
+			if err != nil {
+				return
+			}
+
+			var round *GameRound
+			round, err = hub.store.FindActiveRoundByGameId(id)
+			if err != nil {
+				return
+			}
+
+			answers, err = hub.store.FindAllAnswersByGameAndRound(id, round.ID)
+			members = len(hub.rooms[id].Members)
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(game.Players) != 0 {
+			t.Errorf("game %s still has %d players", id, len(game.Players))
+		}
+
+		if len(answers) != playersPerGame {
+			t.Errorf("game %s has %d answers, want %d", id, len(answers), playersPerGame)
+		}
+
+		if members != 1 {
+			t.Errorf("room of game %s has %d members, want only the moderator", id, members)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// joinCodeAlphabet leaves out characters that are easily confused when
+	// read out loud or off a screen: 0/O, 1/I/L.
+	joinCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	joinCodeLength   = 6
+	joinCodeAttempts = 10
+)
+
+var (
+	ErrPasswordRequired = NewCommandError(ErrCodePasswordRequired, "this game requires a password")
+	ErrWrongPassword    = NewCommandError(ErrCodeWrongPassword, "wrong password")
+)
+
+func randomJoinCode() (string, error) {
+	var b strings.Builder
+
+	for range joinCodeLength {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(joinCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteByte(joinCodeAlphabet[n.Int64()])
+	}
+
+	return b.String(), nil
+}
+
+// NewJoinCode returns a join code that no other game uses.
+func NewJoinCode(store GameStore) (string, error) {
+	for range joinCodeAttempts {
+		code, err := randomJoinCode()
+		if err != nil {
+			return "", err
+		}
+
+		_, err = store.FindGameByCode(code)
+		if errors.Is(err, ErrGameNotFound) {
+			return code, nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("no free join code after %d attempts", joinCodeAttempts)
+}
+
+// NormalizeJoinCode makes codes typed by people comparable to stored ones.
+func NormalizeJoinCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Bcrypt takes tens of milliseconds per password, which would hold up every
+// game if it ran on the hub. Passwords are therefore hashed and compared on
+// the goroutine that reads the client's messages, before they are
+// dispatched, and the handlers only use the results.
+
+// HashPassword hashes a game password. It must not run on the hub.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// SetPasswordHash protects the game with the password hash was made from, or
+// removes the protection when hash is empty.
+func (g *Game) SetPasswordHash(hash string) {
+	g.PasswordHash = hash
+	g.Protected = hash != ""
+}
+
+// CheckPassword verifies the password given when joining the game. The
+// password must already have been found to match verifiedHash, see
+// preparePassword.
+func (g *Game) CheckPassword(password string, verifiedHash string) error {
+	if g.PasswordHash == "" {
+		return nil
+	}
+
+	if password == "" {
+		return ErrPasswordRequired
+	}
+
+	if verifiedHash != g.PasswordHash {
+		return ErrWrongPassword
+	}
+
+	return nil
+}
+
+// preparePassword does the bcrypt work for the password in msg, if it has
+// one: it hashes new game passwords and checks the ones given to join a
+// game. The results are left in msg for the handler.
+func (h *Hub) preparePassword(msg SocketMessage) SocketMessage {
+	switch msg.Type {
+	case "create_game", "set_game_privacy":
+		var payload struct {
+			Password string `json:"password"`
+		}
+
+		if msg.Decode(&payload) != nil || payload.Password == "" {
+			return msg
+		}
+
+		hash, err := HashPassword(payload.Password)
+		if err != nil {
+			log.Println("hash password:", err)
+			return msg
+		}
+
+		msg.passwordHash = hash
+	case "join_game", "spectate":
+		var payload JoinGamePayload
+		if msg.Decode(&payload) != nil || payload.Password == "" {
+			return msg
+		}
+
+		var hash string
+
+		h.Do(func() {
+			var game *Game
+			var err error
+
+			if payload.Code != "" {
+				game, err = h.store.FindGameByCode(NormalizeJoinCode(payload.Code))
+			} else {
+				game, err = h.store.FindGameById(payload.GameID)
+			}
+
+			if err == nil {
+				hash = game.PasswordHash
+			}
+		})
+
+		if hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(payload.Password)) == nil {
+			msg.passwordHash = hash
+		}
+	}
+
+	return msg
+}
+
+// VisibleTo reports whether the game shows up in playerId's game listing.
+// Private games are only listed for their moderator and players.
+func (g *Game) VisibleTo(playerId string) bool {
+	return !g.Private || RoleOf(*g, playerId) != RoleNone
+}
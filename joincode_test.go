@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGamePasswords(t *testing.T) {
+	hub := newTestHub(t)
+
+	mod := connect(t, hub, nil)
+	defer mod.close()
+	mod.mustHello("Mod")
+
+	var game Game
+	mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz", Password: "secret"}, &game)
+
+	if !game.Protected {
+		t.Fatal("game with a password is not protected")
+	}
+
+	player := connect(t, hub, nil)
+	defer player.close()
+	player.mustHello("Player")
+
+	tests := []struct {
+		password string
+		want     ErrorCode
+	}{
+		{"", ErrCodePasswordRequired},
+		{"wrong", ErrCodeWrongPassword},
+		{"secret", ""},
+	}
+
+	for _, tt := range tests {
+		for _, command := range []string{"spectate", "join_game"} {
+			_, err := player.request(command, JoinGamePayload{Code: game.Code, Password: tt.password})
+
+			var reply *replyError
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("%s with %q: %s", command, tt.password, err)
+			case tt.want != "" && (!errors.As(err, &reply) || reply.Code != tt.want):
+				t.Errorf("%s with %q: got %v, want %s", command, tt.password, err, tt.want)
+			}
+		}
+	}
+}
+
+// The hub only compares hashes; the bcrypt work happens before.
+func TestCheckPasswordNeedsVerifiedHash(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var game Game
+	game.SetPasswordHash(hash)
+
+	if err := game.CheckPassword("secret", ""); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("unverified password: got %v, want ErrWrongPassword", err)
+	}
+
+	if err := game.CheckPassword("secret", hash); err != nil {
+		t.Fatalf("verified password: %s", err)
+	}
+
+	msg := SocketMessage{Version: ProtocolVersion, Type: "create_game", Payload: []byte(`{"name":"Quiz","password":"secret"}`)}
+	if msg = (&Hub{}).preparePassword(msg); msg.passwordHash == "" {
+		t.Fatal("create_game password was not hashed")
+	}
+}
+
+func TestSetGamePrivacyKeepsOmittedPassword(t *testing.T) {
+	hub := newTestHub(t)
+
+	mod := connect(t, hub, nil)
+	defer mod.close()
+	mod.mustHello("Mod")
+
+	var game Game
+	mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz", Password: "secret"}, &game)
+
+	mod.mustRequest("set_game_privacy", map[string]any{"gameId": game.ID, "private": true}, &game)
+	if !game.Protected || !game.Private {
+		t.Fatalf("toggling privacy without a password dropped it: %+v", game)
+	}
+
+	mod.mustRequest("set_game_privacy", map[string]any{"gameId": game.ID, "private": false, "password": ""}, &game)
+	if game.Protected || game.Private {
+		t.Fatalf("an empty password did not remove the protection: %+v", game)
+	}
+}
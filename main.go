@@ -1,39 +1,76 @@
 package main
 
-var (
-	connections []*Connection = []*Connection{}
-	games       []*Game       = []*Game{}
-	rounds      []*GameRound  = []*GameRound{}
-	players     []*Player     = []*Player{}
-	answers     []*Answer     = []*Answer{}
+import (
+	"encoding/json"
+	"log"
+	"time"
 )
 
+// SocketMessage is the envelope of every message sent over the socket. See
+// protocol.go for how legacy messages without a version are handled.
 type SocketMessage struct {
-	Type    string `json:"type"`
-	Payload string `json:"payload"`
+	Version   int             `json:"v,omitempty"`
+	Type      string          `json:"type"`
+	RequestID string          `json:"requestId,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	// GameID and Seq are set on events broadcast to a game, see replay.go.
+	GameID string `json:"gameId,omitempty"`
+	Seq    uint64 `json:"seq,omitempty"`
+
+	// passwordHash is the bcrypt hash of the password in the payload, made
+	// or verified before the message reached the hub, see preparePassword.
+	passwordHash string
 }
 
 type Player struct {
 	ID       string `bson:"id" json:"id"`
 	Nickname string `bson:"nickname" json:"nickname"`
+	// TeamID is filled in when players are listed for a game played in
+	// teams. Team membership itself is stored with the game.
+	TeamID string `bson:"-" json:"teamId,omitempty"`
 }
 
 type Game struct {
 	ID            string   `bson:"id" json:"id"`
+	Code          string   `bson:"code" json:"code"`
 	Name          string   `bson:"name" json:"name"`
 	ModeratorUUID string   `bson:"moderatorId" json:"moderatorId"`
 	Players       []string `bson:"players" json:"players"`
+	Private       bool     `bson:"private" json:"private"`
+	Protected     bool     `bson:"protected" json:"protected"`
+	PasswordHash  string   `bson:"passwordHash" json:"-"`
+	// QuestionSetID names the attached question set, if any. Each new round
+	// takes the question at QuestionIndex from the set, which then moves on.
+	QuestionSetID string `bson:"questionSetId" json:"questionSetId,omitempty"`
+	QuestionIndex int    `bson:"questionIndex" json:"questionIndex"`
+	Teams         []Team `bson:"teams" json:"teams"`
+	// AnonymousAnswers hides who submitted answers that are not revealed.
+	AnonymousAnswers bool `bson:"anonymousAnswers" json:"anonymousAnswers"`
+	// RemoveGoneAfter is the number of seconds after which players that are
+	// gone are removed from the game. 0 keeps them.
+	RemoveGoneAfter int `bson:"removeGoneAfter" json:"removeGoneAfter"`
 }
 
+// GameRound is encoded through roundDocument, see round_state.go.
 type GameRound struct {
-	ID       string   `bson:"id" json:"id"`
-	GameID   string   `bson:"gameId" json:"gameId"`
-	Round    int      `bson:"round" json:"round"`
-	Active   bool     `bson:"active" json:"active"`
-	Question string   `bson:"question" json:"question"`
-	Answers  []Answer `bson:"answers" json:"answers"`
-	Started  bool     `bson:"started" json:"started"`
-	Ended    bool     `bson:"ended" json:"ended"`
+	ID       string     `bson:"id" json:"id"`
+	GameID   string     `bson:"gameId" json:"gameId"`
+	Round    int        `bson:"round" json:"round"`
+	State    RoundState `bson:"state" json:"state"`
+	Type     RoundType  `bson:"type,omitempty" json:"type,omitempty"`
+	Question string     `bson:"question" json:"question"`
+	Answers  []Answer   `bson:"answers" json:"answers"`
+	// Options are the choices of a multiple choice round.
+	Options []string `bson:"options,omitempty" json:"options,omitempty"`
+	// Deadline is set for timed rounds; the round ends automatically then.
+	Deadline *time.Time `bson:"deadline,omitempty" json:"deadline,omitempty"`
+	// Key holds the accepted answers answers are judged against when the
+	// round ends. Players must not see it.
+	Key *AnswerKey `bson:"key,omitempty" json:"-"`
+	// Voting is set once the moderator opened a vote on the answers.
+	Voting *Voting `bson:"voting,omitempty" json:"voting,omitempty"`
+	// AnswerMode decides who answers in games played in teams.
+	AnswerMode AnswerMode `bson:"answerMode,omitempty" json:"answerMode,omitempty"`
 }
 
 type Answer struct {
@@ -43,13 +80,88 @@ type Answer struct {
 	RoundID           string `bson:"roundId" json:"roundId"`
 	Text              string `bson:"text" json:"text"`
 	RevealedToPlayers bool   `bson:"revealedToPlayers" json:"revealedToPlayers"`
+	// Status is only set on placeholders sent in place of answers the
+	// recipient may not read, see AnswersFor.
+	Status AnswerStatus `bson:"-" json:"status,omitempty"`
+	// Choice is the option picked in a multiple choice round. It is only
+	// published through the tallies once the round is revealed.
+	Choice *int `bson:"choice,omitempty" json:"-"`
+	// TeamID is the team the player answered for, if any.
+	TeamID string `bson:"teamId,omitempty" json:"teamId,omitempty"`
+	// Points are awarded by the moderator and may be negative. Judging
+	// scores answers automatically until the moderator sets their points.
+	Points           int  `bson:"points" json:"points"`
+	PointsOverridden bool `bson:"pointsOverridden" json:"pointsOverridden"`
+	// Verdict is set when the answer is judged against the round's key.
+	// Once the moderator sets it by hand it is no longer judged again.
+	Verdict           Verdict `bson:"verdict,omitempty" json:"verdict,omitempty"`
+	VerdictOverridden bool    `bson:"verdictOverridden" json:"verdictOverridden"`
+	// Votes is the number of votes the answer got from other players, and
+	// VotePoints what they are worth. They count towards the score on top
+	// of Points.
+	Votes      int `bson:"votes" json:"votes"`
+	VotePoints int `bson:"votePoints" json:"votePoints"`
+}
+
+// QuestionSet is a named, ordered list of questions that can be attached to
+// a game. Sets belong to the player who imported them.
+type QuestionSet struct {
+	ID        string     `bson:"id" json:"id"`
+	Name      string     `bson:"name" json:"name"`
+	OwnerID   string     `bson:"ownerId" json:"ownerId"`
+	Questions []Question `bson:"questions" json:"questions"`
+}
+
+type Question struct {
+	Text string `bson:"text" json:"text"`
+	// Answer is the reference answer, shown to the moderator only.
+	Answer string `bson:"answer,omitempty" json:"answer,omitempty"`
+	Notes  string `bson:"notes,omitempty" json:"notes,omitempty"`
 }
 
 func main() {
-	router := NewServer()
+	config, err := LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %s", err)
+	}
+
+	store, err := NewStore(config)
+	if err != nil {
+		log.Fatalf("Failed to open %s store: %s", config.Store, err)
+	}
+
+	if config.SessionSecret == "" {
+		log.Println("SESSION_SECRET is not set, sessions will not survive a restart")
+		config.SessionSecret = RandomSecret()
+	}
+
+	sessions, err := NewSessionManager(append([]string{config.SessionSecret}, config.SessionPreviousSecrets...), config.SessionTTL)
+	if err != nil {
+		log.Fatalf("Failed to set up sessions: %s", err)
+	}
+
+	hub := NewHub(config, store, sessions)
+	go hub.Run()
+
+	hub.Do(func() {
+		err = hub.RestoreRoundTimers()
+	})
+	if err != nil {
+		log.Printf("Failed to restore round timers: %s", err)
+	}
+
+	router := NewServer(hub)
 
 	router.GET("/ws", router.HandleWebsocket)
 	router.GET("/game/:id", router.GetGameById)
+	router.GET("/game/:id/standings", router.GetGameStandings)
+	router.GET("/game/:id/team-standings", router.GetGameTeamStandings)
+	router.GET("/code/:code", router.GetGameByCode)
+	router.GET("/question-sets", router.GetQuestionSets)
+	router.POST("/question-sets", router.ImportQuestionSet)
+	router.GET("/question-sets/:id", router.ExportQuestionSet)
+	router.DELETE("/question-sets/:id", router.DeleteQuestionSet)
+	router.RegisterAPI(router.Group("/api/v1"))
 	router.Static("/assets", "./public/assets")
 	router.StaticFile("/", "./public/index.html")
 	router.StaticFile("/vite.svg", "./public/vite.svg")
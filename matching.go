@@ -0,0 +1,277 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Verdict is how well an answer matches the accepted answers of its round.
+type Verdict string
+
+const (
+	VerdictCorrect Verdict = "correct"
+	VerdictClose   Verdict = "close"
+	VerdictWrong   Verdict = "wrong"
+)
+
+func (v Verdict) Valid() bool {
+	return v == VerdictCorrect || v == VerdictClose || v == VerdictWrong
+}
+
+// AcceptedAnswer is one correct answer of a round, with other spellings that
+// count the same.
+type AcceptedAnswer struct {
+	Text    string   `bson:"text" json:"text"`
+	Aliases []string `bson:"aliases" json:"aliases"`
+}
+
+// AnswerKey holds what the moderator knows about the answer to a round's
+// question. It is never sent to players.
+type AnswerKey struct {
+	Answers []AcceptedAnswer `bson:"answers" json:"answers"`
+	// Tolerance is the edit distance still judged correct, CloseTolerance
+	// the one judged close. Both are capped for short answers, see Judge.
+	Tolerance      int `bson:"tolerance" json:"tolerance"`
+	CloseTolerance int `bson:"closeTolerance" json:"closeTolerance"`
+	// CorrectPoints and ClosePoints are awarded automatically when answers
+	// are judged. Wrong answers get no points.
+	CorrectPoints int `bson:"correctPoints" json:"correctPoints"`
+	ClosePoints   int `bson:"closePoints" json:"closePoints"`
+}
+
+// Points returns what an answer with verdict v scores.
+func (k AnswerKey) Points(v Verdict) int {
+	switch v {
+	case VerdictCorrect:
+		return k.CorrectPoints
+	case VerdictClose:
+		return k.ClosePoints
+	}
+
+	return 0
+}
+
+var foldAccents = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// NormalizeAnswer makes answers comparable: lower case, without accents and
+// without any whitespace.
+func NormalizeAnswer(s string) string {
+	folded, _, err := transform.String(foldAccents, s)
+	if err != nil {
+		folded = s
+	}
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+
+		return unicode.ToLower(r)
+	}, folded)
+}
+
+// EditDistance is the Levenshtein distance between a and b in runes.
+func EditDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// Judge compares text against every accepted answer and alias. So that "2"
+// is not accepted for "3", the tolerances are capped at a quarter (correct)
+// and half (close) of the accepted answer's length.
+func (k AnswerKey) Judge(text string) Verdict {
+	given := NormalizeAnswer(text)
+	if given == "" {
+		return VerdictWrong
+	}
+
+	verdict := VerdictWrong
+
+	for _, accepted := range k.Answers {
+		for _, candidate := range append([]string{accepted.Text}, accepted.Aliases...) {
+			want := NormalizeAnswer(candidate)
+			if want == "" {
+				continue
+			}
+
+			length := len([]rune(want))
+			distance := EditDistance(given, want)
+
+			if distance <= min(k.Tolerance, length/4) {
+				return VerdictCorrect
+			}
+
+			if distance <= min(k.CloseTolerance, length/2) {
+				verdict = VerdictClose
+			}
+		}
+	}
+
+	return verdict
+}
+
+// JudgeAnswers gives every answer of round a verdict, leaving the ones the
+// moderator overrode alone, and scores them unless the moderator set their
+// points by hand.
+func (h *Hub) JudgeAnswers(round GameRound) error {
+	if round.Key == nil {
+		return nil
+	}
+
+	answers, err := h.store.FindAllAnswersByGameAndRound(round.GameID, round.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, answer := range answers {
+		if answer.VerdictOverridden {
+			continue
+		}
+
+		answer.Verdict = round.Key.Judge(round.AnswerText(answer))
+		if !answer.PointsOverridden {
+			answer.Points = round.Key.Points(answer.Verdict)
+		}
+
+		err = h.store.UpdateAnswer(answer)
+		if err != nil {
+			return err
+		}
+	}
+
+	h.BroadcastAnswers(round.GameID)
+	h.BroadcastLeaderboard(round.GameID)
+
+	return nil
+}
+
+// NewAnswerKey builds the key of a round from the server's defaults.
+func (h *Hub) NewAnswerKey(answers []AcceptedAnswer) *AnswerKey {
+	return &AnswerKey{
+		Answers:        answers,
+		Tolerance:      h.config.MatchTolerance,
+		CloseTolerance: h.config.MatchCloseTolerance,
+		CorrectPoints:  h.config.CorrectPoints,
+		ClosePoints:    h.config.ClosePoints,
+	}
+}
+
+// SetAnswerKey sets the accepted answers of the active round. Rounds that
+// already ended are judged again right away.
+func (c *Connection) SetAnswerKey(msg SocketMessage) (*AnswerKey, error) {
+	var payload AnswerKeyPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	round, err := c.GetActiveRound()
+	if err != nil {
+		return nil, err
+	}
+
+	round.Key = nil
+
+	if len(payload.Answers) > 0 {
+		round.Key = c.hub.NewAnswerKey(payload.Answers)
+
+		if payload.Tolerance != nil {
+			round.Key.Tolerance = *payload.Tolerance
+		}
+
+		if payload.CloseTolerance != nil {
+			round.Key.CloseTolerance = *payload.CloseTolerance
+		}
+
+		if payload.CorrectPoints != nil {
+			round.Key.CorrectPoints = *payload.CorrectPoints
+		}
+
+		if payload.ClosePoints != nil {
+			round.Key.ClosePoints = *payload.ClosePoints
+		}
+	}
+
+	err = c.hub.store.UpdateRound(*round)
+	if err != nil {
+		return nil, err
+	}
+
+	if round.State == RoundClosed || round.State == RoundRevealed {
+		err = c.hub.JudgeAnswers(*round)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return round.Key, nil
+}
+
+// SetAnswerVerdict lets the moderator overrule the automatic verdict of an
+// answer. The answer is scored for the new verdict when its round has a key,
+// unless the moderator set its points by hand.
+func (c *Connection) SetAnswerVerdict(msg SocketMessage) (*Answer, error) {
+	var payload AnswerVerdictPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	if !payload.Verdict.Valid() {
+		return nil, NewCommandError(ErrCodeInvalidPayload, "unknown verdict %q", payload.Verdict)
+	}
+
+	answer, err := c.hub.store.FindAnswerById(payload.AnswerID)
+	if err != nil {
+		return nil, err
+	}
+
+	round, err := c.hub.store.FindRoundById(answer.RoundID)
+	if err != nil {
+		return nil, err
+	}
+
+	answer.Verdict = payload.Verdict
+	answer.VerdictOverridden = true
+
+	if round.Key != nil && !answer.PointsOverridden {
+		answer.Points = round.Key.Points(answer.Verdict)
+	}
+
+	err = c.hub.store.UpdateAnswer(*answer)
+	if err != nil {
+		return nil, err
+	}
+
+	c.hub.BroadcastAnswers(answer.GameID)
+	c.hub.BroadcastLeaderboard(answer.GameID)
+
+	return answer, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import "testing"
+
+func TestNormalizeAnswer(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Paris", "paris"},
+		{"  New   York ", "newyork"},
+		{"Ciudad\tde\nMéxico", "ciudaddemexico"},
+		{"Crème Brûlée", "cremebrulee"},
+		{"ÅNGSTRÖM", "angstrom"},
+		{"Straße", "straße"},
+		{"", ""},
+		{" \t ", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeAnswer(tt.in); got != tt.want {
+			t.Errorf("NormalizeAnswer(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"paris", "paris", 0},
+		{"paris", "pari", 1},
+		{"paris", "parus", 1},
+		{"paris", "aparis", 1},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		// Runes, not bytes.
+		{"straße", "strasse", 2},
+		{"日本", "日本語", 1},
+	}
+
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestJudge(t *testing.T) {
+	key := AnswerKey{
+		Answers: []AcceptedAnswer{
+			{Text: "Paris"},
+			{Text: "Mississippi"},
+			{Text: "3", Aliases: []string{"three"}},
+		},
+		Tolerance:      1,
+		CloseTolerance: 3,
+	}
+
+	tests := []struct {
+		text string
+		want Verdict
+	}{
+		{"paris", VerdictCorrect},
+		{" PÄRIS ", VerdictCorrect},
+		// "paris" has 5 runes: a quarter allows 1 edit, half allows 2.
+		{"pari", VerdictCorrect},
+		{"par", VerdictClose},
+		{"pa", VerdictWrong},
+		// The tolerance caps what a quarter of "mississippi" would allow.
+		{"misisipi", VerdictClose},
+		{"missisippi", VerdictCorrect},
+		// One rune answers allow no edits at all.
+		{"3", VerdictCorrect},
+		{"2", VerdictWrong},
+		{"thr", VerdictClose},
+		{"Three", VerdictCorrect},
+		{"", VerdictWrong},
+		{"London", VerdictWrong},
+	}
+
+	for _, tt := range tests {
+		if got := key.Judge(tt.text); got != tt.want {
+			t.Errorf("Judge(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+
+	// Generous tolerances are still capped by the length.
+	generous := AnswerKey{Answers: []AcceptedAnswer{{Text: "abcd"}}, Tolerance: 10, CloseTolerance: 10}
+
+	for text, want := range map[string]Verdict{"abce": VerdictCorrect, "abxy": VerdictClose, "axyz": VerdictWrong} {
+		if got := generous.Judge(text); got != want {
+			t.Errorf("generous Judge(%q) = %s, want %s", text, got, want)
+		}
+	}
+}
+
+// Points the moderator set by hand survive judging again and a new verdict.
+func TestJudgingKeepsPointsSetByHand(t *testing.T) {
+	hub := newTestHub(t)
+
+	mod := connect(t, hub, nil)
+	defer mod.close()
+	mod.mustHello("Mod")
+
+	var game Game
+	mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &game)
+	mod.mustRequest("start_round", GameIDPayload{GameID: game.ID}, nil)
+
+	player := connect(t, hub, nil)
+	defer player.close()
+	player.mustHello("Player")
+	player.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+	player.mustRequest("set_answer", TextPayload{Text: "Paris"}, nil)
+
+	mod.mustRequest("set_accepted_answers", AnswerKeyPayload{Answers: []AcceptedAnswer{{Text: "Paris"}}}, nil)
+	mod.mustRequest("end_round", GameIDPayload{GameID: game.ID}, nil)
+
+	answer := func() Answer {
+		var answers []Answer
+		var err error
+
+		hub.Do(func() {
+			answers, err = hub.store.FindAnswersByGameId(game.ID)
+		})
+		if err != nil || len(answers) != 1 {
+			t.Fatalf("answers %v: %v", answers, err)
+		}
+
+		return answers[0]
+	}
+
+	if a := answer(); a.Verdict != VerdictCorrect || a.Points != 1 {
+		t.Fatalf("judged %s for %d points, want correct for 1", a.Verdict, a.Points)
+	}
+
+	points := 5
+	mod.mustRequest("set_answer_points", AnswerPointsPayload{AnswerID: answer().ID, Points: &points}, nil)
+
+	key := AnswerKeyPayload{Answers: []AcceptedAnswer{{Text: "Paris", Aliases: []string{"Paname"}}}}
+	mod.mustRequest("set_accepted_answers", key, nil)
+
+	if a := answer(); a.Points != 5 {
+		t.Fatalf("judging again reset the points to %d", a.Points)
+	}
+
+	mod.mustRequest("set_answer_verdict", AnswerVerdictPayload{AnswerID: answer().ID, Verdict: VerdictWrong}, nil)
+
+	if a := answer(); a.Verdict != VerdictWrong || a.Points != 5 {
+		t.Fatalf("new verdict left %s for %d points, want wrong for 5", a.Verdict, a.Points)
+	}
+}
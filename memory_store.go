@@ -0,0 +1,490 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+// MemoryStore keeps everything in process memory. It is the default store and
+// loses all state on restart.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	games   []*Game
+	rounds  []*GameRound
+	players []*Player
+	answers []*Answer
+	sets    []*QuestionSet
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		games:   []*Game{},
+		rounds:  []*GameRound{},
+		players: []*Player{},
+		answers: []*Answer{},
+		sets:    []*QuestionSet{},
+	}
+}
+
+func cloneGame(g Game) Game {
+	g.Players = slices.Clone(g.Players)
+	g.Teams = slices.Clone(g.Teams)
+
+	for i := range g.Teams {
+		g.Teams[i].Players = slices.Clone(g.Teams[i].Players)
+	}
+
+	return g
+}
+
+func cloneRound(r GameRound) GameRound {
+	r.Answers = slices.Clone(r.Answers)
+	return r
+}
+
+func cloneQuestionSet(q QuestionSet) QuestionSet {
+	q.Questions = slices.Clone(q.Questions)
+	return q
+}
+
+func (s *MemoryStore) FindAllGames() ([]Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := []Game{}
+	for _, g := range s.games {
+		res = append(res, cloneGame(*g))
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) FindGameById(id string) (*Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, g := range s.games {
+		if g.ID == id {
+			game := cloneGame(*g)
+			return &game, nil
+		}
+	}
+
+	return nil, ErrGameNotFound
+}
+
+func (s *MemoryStore) FindGameByCode(code string) (*Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, g := range s.games {
+		if g.Code == code {
+			game := cloneGame(*g)
+			return &game, nil
+		}
+	}
+
+	return nil, ErrGameNotFound
+}
+
+func (s *MemoryStore) FindGamesByPlayerId(playerId string) ([]Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := []Game{}
+	for _, g := range s.games {
+		if slices.Contains(g.Players, playerId) {
+			res = append(res, cloneGame(*g))
+		}
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) FindGamesByModeratorId(moderatorId string) ([]Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := []Game{}
+	for _, g := range s.games {
+		if g.ModeratorUUID == moderatorId {
+			res = append(res, cloneGame(*g))
+		}
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) CreateGame(game Game) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := cloneGame(game)
+	s.games = append(s.games, &g)
+	return nil
+}
+
+func (s *MemoryStore) UpdateGame(game Game) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, g := range s.games {
+		if g.ID == game.ID {
+			updated := cloneGame(game)
+			s.games[i] = &updated
+			return nil
+		}
+	}
+
+	return ErrGameNotFound
+}
+
+func (s *MemoryStore) UpdateGamePlayers(id string, players []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, g := range s.games {
+		if g.ID == id {
+			g.Players = slices.Clone(players)
+			return nil
+		}
+	}
+
+	return ErrGameNotFound
+}
+
+func (s *MemoryStore) DeleteGame(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, g := range s.games {
+		if g.ID == id {
+			s.games = slices.Delete(s.games, i, i+1)
+			return nil
+		}
+	}
+
+	return ErrGameNotFound
+}
+
+func (s *MemoryStore) FindPlayerById(id string) (*Player, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.players {
+		if p.ID == id {
+			player := *p
+			return &player, nil
+		}
+	}
+
+	return nil, ErrPlayerNotFound
+}
+
+func (s *MemoryStore) FindPlayersByIds(ids []string) ([]Player, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := []Player{}
+	for _, p := range s.players {
+		if slices.Contains(ids, p.ID) {
+			res = append(res, *p)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) CreatePlayer(player Player) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.players = append(s.players, &player)
+	return nil
+}
+
+func (s *MemoryStore) UpdatePlayer(player Player) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.players {
+		if p.ID == player.ID {
+			s.players[i] = &player
+			return nil
+		}
+	}
+
+	return ErrPlayerNotFound
+}
+
+func (s *MemoryStore) DeletePlayer(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.players {
+		if p.ID == id {
+			s.players = slices.Delete(s.players, i, i+1)
+			return nil
+		}
+	}
+
+	return ErrPlayerNotFound
+}
+
+func (s *MemoryStore) FindRoundById(id string) (*GameRound, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.rounds {
+		if r.ID == id {
+			round := cloneRound(*r)
+			return &round, nil
+		}
+	}
+
+	return nil, ErrRoundNotFound
+}
+
+func (s *MemoryStore) FindRoundsByGameId(gameId string) ([]GameRound, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := []GameRound{}
+	for _, r := range s.rounds {
+		if r.GameID == gameId {
+			res = append(res, cloneRound(*r))
+		}
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) FindActiveRoundByGameId(gameId string) (*GameRound, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.rounds {
+		if r.GameID == gameId && r.Active() {
+			round := cloneRound(*r)
+			return &round, nil
+		}
+	}
+
+	return nil, ErrRoundNotFound
+}
+
+func (s *MemoryStore) CreateRound(round GameRound) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := cloneRound(round)
+	s.rounds = append(s.rounds, &r)
+	return nil
+}
+
+func (s *MemoryStore) UpdateRound(round GameRound) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.rounds {
+		if r.ID == round.ID {
+			updated := cloneRound(round)
+			s.rounds[i] = &updated
+			return nil
+		}
+	}
+
+	return ErrRoundNotFound
+}
+
+func (s *MemoryStore) DeleteRound(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.rounds {
+		if r.ID == id {
+			s.rounds = slices.Delete(s.rounds, i, i+1)
+			return nil
+		}
+	}
+
+	return ErrRoundNotFound
+}
+
+func (s *MemoryStore) DeleteRoundsByGameId(gameId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rounds = slices.DeleteFunc(s.rounds, func(r *GameRound) bool {
+		return r.GameID == gameId
+	})
+	return nil
+}
+
+func (s *MemoryStore) FindAnswerById(id string) (*Answer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, a := range s.answers {
+		if a.ID == id {
+			answer := *a
+			return &answer, nil
+		}
+	}
+
+	return nil, ErrAnswerNotFound
+}
+
+func (s *MemoryStore) FindAnswerByPlayer(gameId string, roundId string, playerId string) (*Answer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, a := range s.answers {
+		if a.GameID == gameId && a.RoundID == roundId && a.PlayerID == playerId {
+			answer := *a
+			return &answer, nil
+		}
+	}
+
+	return nil, ErrAnswerNotFound
+}
+
+func (s *MemoryStore) FindAllAnswersByGameAndRound(gameId string, roundId string) ([]Answer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := []Answer{}
+	for _, a := range s.answers {
+		if a.GameID == gameId && a.RoundID == roundId {
+			res = append(res, *a)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) FindAnswersByGameId(gameId string) ([]Answer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := []Answer{}
+	for _, a := range s.answers {
+		if a.GameID == gameId {
+			res = append(res, *a)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) CreateAnswer(answer Answer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.answers = append(s.answers, &answer)
+	return nil
+}
+
+func (s *MemoryStore) UpdateAnswer(answer Answer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, a := range s.answers {
+		if a.ID == answer.ID {
+			s.answers[i] = &answer
+			return nil
+		}
+	}
+
+	return ErrAnswerNotFound
+}
+
+func (s *MemoryStore) DeleteAnswer(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, a := range s.answers {
+		if a.ID == id {
+			s.answers = slices.Delete(s.answers, i, i+1)
+			return nil
+		}
+	}
+
+	return ErrAnswerNotFound
+}
+
+func (s *MemoryStore) DeleteAnswersByGameId(gameId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.answers = slices.DeleteFunc(s.answers, func(a *Answer) bool {
+		return a.GameID == gameId
+	})
+	return nil
+}
+
+func (s *MemoryStore) FindQuestionSetById(id string) (*QuestionSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, q := range s.sets {
+		if q.ID == id {
+			set := cloneQuestionSet(*q)
+			return &set, nil
+		}
+	}
+
+	return nil, ErrQuestionSetNotFound
+}
+
+func (s *MemoryStore) FindQuestionSetsByOwnerId(ownerId string) ([]QuestionSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := []QuestionSet{}
+	for _, q := range s.sets {
+		if q.OwnerID == ownerId {
+			res = append(res, cloneQuestionSet(*q))
+		}
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) CreateQuestionSet(set QuestionSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := cloneQuestionSet(set)
+	s.sets = append(s.sets, &q)
+	return nil
+}
+
+func (s *MemoryStore) UpdateQuestionSet(set QuestionSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, q := range s.sets {
+		if q.ID == set.ID {
+			updated := cloneQuestionSet(set)
+			s.sets[i] = &updated
+			return nil
+		}
+	}
+
+	return ErrQuestionSetNotFound
+}
+
+func (s *MemoryStore) DeleteQuestionSet(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, q := range s.sets {
+		if q.ID == id {
+			s.sets = slices.Delete(s.sets, i, i+1)
+			return nil
+		}
+	}
+
+	return ErrQuestionSetNotFound
+}
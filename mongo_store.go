@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const mongoTimeout = 5 * time.Second
+
+// MongoStore persists everything in MongoDB, one collection per entity. All
+// documents are addressed by their "id" field rather than by "_id".
+type MongoStore struct {
+	client  *mongo.Client
+	games   *mongo.Collection
+	rounds  *mongo.Collection
+	players *mongo.Collection
+	answers *mongo.Collection
+	sets    *mongo.Collection
+}
+
+func NewMongoStore(uri string, database string) (*MongoStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database(database)
+
+	s := &MongoStore{
+		client:  client,
+		games:   db.Collection("games"),
+		rounds:  db.Collection("rounds"),
+		players: db.Collection("players"),
+		answers: db.Collection("answers"),
+		sets:    db.Collection("questionSets"),
+	}
+
+	for _, c := range []*mongo.Collection{s.games, s.rounds, s.players, s.answers, s.sets} {
+		_, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.games.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func mongoFindOne[T any](c *mongo.Collection, filter bson.M, notFound error) (*T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+
+	var res T
+	err := c.FindOne(ctx, filter).Decode(&res)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, notFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func mongoFind[T any](c *mongo.Collection, filter bson.M) ([]T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+
+	cursor, err := c.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []T{}
+	err = cursor.All(ctx, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func mongoInsert(c *mongo.Collection, doc any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+
+	_, err := c.InsertOne(ctx, doc)
+	return err
+}
+
+func mongoReplace(c *mongo.Collection, id string, doc any, notFound error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+
+	res, err := c.ReplaceOne(ctx, bson.M{"id": id}, doc)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+func mongoDelete(c *mongo.Collection, id string, notFound error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+
+	res, err := c.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+func mongoDeleteMany(c *mongo.Collection, filter bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+
+	_, err := c.DeleteMany(ctx, filter)
+	return err
+}
+
+func (s *MongoStore) FindAllGames() ([]Game, error) {
+	return mongoFind[Game](s.games, bson.M{})
+}
+
+func (s *MongoStore) FindGameById(id string) (*Game, error) {
+	return mongoFindOne[Game](s.games, bson.M{"id": id}, ErrGameNotFound)
+}
+
+func (s *MongoStore) FindGameByCode(code string) (*Game, error) {
+	return mongoFindOne[Game](s.games, bson.M{"code": code}, ErrGameNotFound)
+}
+
+func (s *MongoStore) FindGamesByPlayerId(playerId string) ([]Game, error) {
+	return mongoFind[Game](s.games, bson.M{"players": playerId})
+}
+
+func (s *MongoStore) FindGamesByModeratorId(moderatorId string) ([]Game, error) {
+	return mongoFind[Game](s.games, bson.M{"moderatorId": moderatorId})
+}
+
+func (s *MongoStore) CreateGame(game Game) error {
+	return mongoInsert(s.games, game)
+}
+
+func (s *MongoStore) UpdateGame(game Game) error {
+	return mongoReplace(s.games, game.ID, game, ErrGameNotFound)
+}
+
+func (s *MongoStore) UpdateGamePlayers(id string, players []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+
+	res, err := s.games.UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"players": players}})
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrGameNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoStore) DeleteGame(id string) error {
+	return mongoDelete(s.games, id, ErrGameNotFound)
+}
+
+func (s *MongoStore) FindPlayerById(id string) (*Player, error) {
+	return mongoFindOne[Player](s.players, bson.M{"id": id}, ErrPlayerNotFound)
+}
+
+func (s *MongoStore) FindPlayersByIds(ids []string) ([]Player, error) {
+	return mongoFind[Player](s.players, bson.M{"id": bson.M{"$in": ids}})
+}
+
+func (s *MongoStore) CreatePlayer(player Player) error {
+	return mongoInsert(s.players, player)
+}
+
+func (s *MongoStore) UpdatePlayer(player Player) error {
+	return mongoReplace(s.players, player.ID, player, ErrPlayerNotFound)
+}
+
+func (s *MongoStore) DeletePlayer(id string) error {
+	return mongoDelete(s.players, id, ErrPlayerNotFound)
+}
+
+func (s *MongoStore) FindRoundById(id string) (*GameRound, error) {
+	return mongoFindOne[GameRound](s.rounds, bson.M{"id": id}, ErrRoundNotFound)
+}
+
+func (s *MongoStore) FindRoundsByGameId(gameId string) ([]GameRound, error) {
+	return mongoFind[GameRound](s.rounds, bson.M{"gameId": gameId})
+}
+
+func (s *MongoStore) FindActiveRoundByGameId(gameId string) (*GameRound, error) {
+	return mongoFindOne[GameRound](s.rounds, bson.M{"gameId": gameId, "active": true}, ErrRoundNotFound)
+}
+
+func (s *MongoStore) CreateRound(round GameRound) error {
+	return mongoInsert(s.rounds, round)
+}
+
+func (s *MongoStore) UpdateRound(round GameRound) error {
+	return mongoReplace(s.rounds, round.ID, round, ErrRoundNotFound)
+}
+
+func (s *MongoStore) DeleteRound(id string) error {
+	return mongoDelete(s.rounds, id, ErrRoundNotFound)
+}
+
+func (s *MongoStore) DeleteRoundsByGameId(gameId string) error {
+	return mongoDeleteMany(s.rounds, bson.M{"gameId": gameId})
+}
+
+func (s *MongoStore) FindAnswerById(id string) (*Answer, error) {
+	return mongoFindOne[Answer](s.answers, bson.M{"id": id}, ErrAnswerNotFound)
+}
+
+func (s *MongoStore) FindAnswerByPlayer(gameId string, roundId string, playerId string) (*Answer, error) {
+	return mongoFindOne[Answer](s.answers, bson.M{"gameId": gameId, "roundId": roundId, "playerId": playerId}, ErrAnswerNotFound)
+}
+
+func (s *MongoStore) FindAllAnswersByGameAndRound(gameId string, roundId string) ([]Answer, error) {
+	return mongoFind[Answer](s.answers, bson.M{"gameId": gameId, "roundId": roundId})
+}
+
+func (s *MongoStore) FindAnswersByGameId(gameId string) ([]Answer, error) {
+	return mongoFind[Answer](s.answers, bson.M{"gameId": gameId})
+}
+
+func (s *MongoStore) CreateAnswer(answer Answer) error {
+	return mongoInsert(s.answers, answer)
+}
+
+func (s *MongoStore) UpdateAnswer(answer Answer) error {
+	return mongoReplace(s.answers, answer.ID, answer, ErrAnswerNotFound)
+}
+
+func (s *MongoStore) DeleteAnswer(id string) error {
+	return mongoDelete(s.answers, id, ErrAnswerNotFound)
+}
+
+func (s *MongoStore) DeleteAnswersByGameId(gameId string) error {
+	return mongoDeleteMany(s.answers, bson.M{"gameId": gameId})
+}
+
+func (s *MongoStore) FindQuestionSetById(id string) (*QuestionSet, error) {
+	return mongoFindOne[QuestionSet](s.sets, bson.M{"id": id}, ErrQuestionSetNotFound)
+}
+
+func (s *MongoStore) FindQuestionSetsByOwnerId(ownerId string) ([]QuestionSet, error) {
+	return mongoFind[QuestionSet](s.sets, bson.M{"ownerId": ownerId})
+}
+
+func (s *MongoStore) CreateQuestionSet(set QuestionSet) error {
+	return mongoInsert(s.sets, set)
+}
+
+func (s *MongoStore) UpdateQuestionSet(set QuestionSet) error {
+	return mongoReplace(s.sets, set.ID, set, ErrQuestionSetNotFound)
+}
+
+func (s *MongoStore) DeleteQuestionSet(id string) error {
+	return mongoDelete(s.sets, id, ErrQuestionSetNotFound)
+}
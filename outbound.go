@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// QueuePolicy decides what happens when a connection's outbound queue is
+// full, usually because the client reads slower than we broadcast.
+type QueuePolicy string
+
+const (
+	// QueueDropOldest discards the oldest queued message that is not a reply
+	// to make room.
+	QueueDropOldest QueuePolicy = "drop_oldest"
+	// QueueCoalesce makes room by dropping queued snapshots that a newer
+	// message of the same type and game replaces, see snapshotMessages.
+	// Events are never dropped: if nothing can be coalesced the connection
+	// is closed, and the client catches up when it resumes.
+	QueueCoalesce QueuePolicy = "coalesce"
+	// QueueDisconnect closes the connection.
+	QueueDisconnect QueuePolicy = "disconnect"
+)
+
+// snapshotMessages are the message types that carry the whole of some
+// state, so a newer one makes older ones of the same game redundant. Every
+// other message is an event or a reply that the client has to get.
+var snapshotMessages = map[string]bool{
+	"all_answers":           true,
+	"get_rounds":            true,
+	"get_games":             true,
+	"get_connected_players": true,
+	"set_text":              true,
+	"leaderboard":           true,
+	"team_leaderboard":      true,
+	"round_timer":           true,
+	"presence":              true,
+	"buzzer":                true,
+	"ballot":                true,
+}
+
+// replyMessages answer a request. Clients match them to their requests by
+// request ID, so they are never dropped or coalesced to make room.
+var replyMessages = map[string]bool{
+	"ack":   true,
+	"error": true,
+}
+
+func ParseQueuePolicy(s string) (QueuePolicy, error) {
+	switch p := QueuePolicy(s); p {
+	case QueueDropOldest, QueueCoalesce, QueueDisconnect:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("unknown queue policy %q", s)
+}
+
+type outboundMessage struct {
+	kind string
+	game string
+	data []byte
+}
+
+// supersedes reports whether m makes the queued message old redundant.
+func (m outboundMessage) supersedes(old outboundMessage) bool {
+	if replyMessages[old.kind] {
+		return false
+	}
+
+	return snapshotMessages[old.kind] && m.kind == old.kind && m.game == old.game
+}
+
+// Write queues data for the write pump. kind is the message type and game
+// the game it belongs to, if any; both are used to coalesce messages when
+// the queue is full.
+func (c *Connection) Write(kind string, game string, data []byte) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.closed {
+		return fmt.Errorf("connection closed")
+	}
+
+	msg := outboundMessage{kind: kind, game: game, data: data}
+
+	select {
+	case c.send <- msg:
+		return nil
+	default:
+	}
+
+	switch c.hub.config.QueuePolicy {
+	case QueueDisconnect:
+		c.closeSend()
+		return fmt.Errorf("send queue full, disconnecting")
+	case QueueCoalesce:
+		if !c.coalesce(msg) {
+			c.closeSend()
+			return fmt.Errorf("send queue full of events, disconnecting")
+		}
+	default:
+		if !c.dropOldest(msg) {
+			c.closeSend()
+			return fmt.Errorf("send queue full of replies, disconnecting")
+		}
+	}
+
+	return nil
+}
+
+// dropOldest drops the oldest queued message that is not a reply and queues
+// msg instead. It reports false if only replies are queued. The caller must
+// hold sendMu.
+func (c *Connection) dropOldest(msg outboundMessage) bool {
+	pending := []outboundMessage{}
+
+	for len(c.send) > 0 {
+		pending = append(pending, <-c.send)
+	}
+
+	i := slices.IndexFunc(pending, func(m outboundMessage) bool {
+		return !replyMessages[m.kind]
+	})
+
+	if i >= 0 {
+		pending = append(slices.Delete(pending, i, i+1), msg)
+	}
+
+	for _, m := range pending {
+		c.send <- m
+	}
+
+	return i >= 0
+}
+
+// coalesce drops every queued snapshot that a later queued message or msg
+// supersedes, and queues msg if that made room. Otherwise it leaves the
+// queue as it was and reports false. The caller must hold sendMu.
+func (c *Connection) coalesce(msg outboundMessage) bool {
+	pending := []outboundMessage{}
+
+	for len(c.send) > 0 {
+		pending = append(pending, <-c.send)
+	}
+
+	kept := []outboundMessage{}
+	all := append(slices.Clone(pending), msg)
+
+	for i, m := range all {
+		superseded := slices.ContainsFunc(all[i+1:], func(later outboundMessage) bool {
+			return later.supersedes(m)
+		})
+
+		if !superseded {
+			kept = append(kept, m)
+		}
+	}
+
+	ok := len(kept) <= cap(c.send)
+	if !ok {
+		kept = pending
+	}
+
+	for _, m := range kept {
+		c.send <- m
+	}
+
+	return ok
+}
+
+// closeSend stops the write pump, which then closes the socket. The caller
+// must hold sendMu.
+func (c *Connection) closeSend() {
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+	close(c.send)
+}
+
+// Close stops the write pump once everything queued so far has been written.
+func (c *Connection) Close() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	c.closeSend()
+}
+
+// writePump is the only goroutine that writes to the socket. It also pings
+// the client. If a write fails or times out it closes the socket, which ends
+// Listen and so unregisters the connection.
+func (c *Connection) writePump() {
+	ping := time.NewTicker(c.hub.config.PingInterval)
+
+	defer func() {
+		ping.Stop()
+		c.Conn.Close()
+	}()
+
+	for {
+		var err error
+
+		select {
+		case msg, ok := <-c.send:
+			if !ok {
+				c.writeFrame(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+
+			err = c.writeFrame(websocket.TextMessage, msg.data)
+		case <-ping.C:
+			err = c.writeFrame(websocket.PingMessage, nil)
+		}
+
+		if err != nil {
+			log.Println("write:", err)
+			return
+		}
+	}
+}
+
+func (c *Connection) writeFrame(messageType int, data []byte) error {
+	err := c.Conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteWait))
+	if err != nil {
+		return err
+	}
+
+	return c.Conn.WriteMessage(messageType, data)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// queued empties the send queue of a connection without a write pump and
+// returns the types of the messages it held.
+func queued(c *Connection) []string {
+	kinds := []string{}
+
+	for len(c.send) > 0 {
+		kinds = append(kinds, (<-c.send).kind)
+	}
+
+	return kinds
+}
+
+func newQueueTestConnection(policy QueuePolicy, size int) *Connection {
+	hub := &Hub{config: Config{SendQueueSize: size, QueuePolicy: policy}}
+	return NewConnection(hub, nil, nil)
+}
+
+func TestCoalesceReplacesSnapshotsOnly(t *testing.T) {
+	c := newQueueTestConnection(QueueCoalesce, 4)
+
+	writes := []struct{ kind, game string }{
+		{"all_answers", "g1"},
+		{"player_connected", "g1"},
+		{"all_answers", "g2"},
+		{"get_rounds", "g1"},
+		// The queue is full from here on.
+		{"all_answers", "g1"},
+		{"get_rounds", "g1"},
+	}
+
+	for _, w := range writes {
+		err := c.Write(w.kind, w.game, []byte("{}"))
+		if err != nil {
+			t.Fatalf("write %s: %s", w.kind, err)
+		}
+	}
+
+	want := []string{"player_connected", "all_answers", "all_answers", "get_rounds"}
+	if got := queued(c); !slices.Equal(got, want) {
+		t.Fatalf("queued %v, want %v", got, want)
+	}
+}
+
+func TestCoalesceDisconnectsInsteadOfDroppingEvents(t *testing.T) {
+	c := newQueueTestConnection(QueueCoalesce, 2)
+
+	for _, kind := range []string{"player_connected", "leave_game"} {
+		err := c.Write(kind, "g1", []byte("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := c.Write("game_deleted", "g1", []byte("{}"))
+	if err == nil {
+		t.Fatal("write to a queue full of events succeeded")
+	}
+
+	if !c.closed {
+		t.Fatal("connection is still open")
+	}
+
+	// Everything queued before is still written before the socket closes.
+	want := []string{"player_connected", "leave_game"}
+	if got := queued(c); !slices.Equal(got, want) {
+		t.Fatalf("queued %v, want %v", got, want)
+	}
+}
+
+func TestFullQueuesKeepReplies(t *testing.T) {
+	tests := []struct {
+		policy QueuePolicy
+		writes []string
+		want   []string
+	}{
+		{QueueCoalesce, []string{"ack", "all_answers", "ack", "ack"}, []string{"ack", "all_answers", "ack"}},
+		{QueueCoalesce, []string{"ack", "all_answers", "ack", "all_answers"}, []string{"ack", "ack", "all_answers"}},
+		{QueueDropOldest, []string{"all_answers", "ack", "error", "ack"}, []string{"ack", "error", "ack"}},
+		{QueueDropOldest, []string{"ack", "error", "ack", "all_answers"}, []string{"ack", "error", "ack"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			c := newQueueTestConnection(tt.policy, 3)
+
+			for _, kind := range tt.writes {
+				c.Write(kind, "g1", []byte("{}"))
+			}
+
+			if got := queued(c); !slices.Equal(got, tt.want) {
+				t.Fatalf("queued %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
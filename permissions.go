@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// Role is what a connection is allowed to do within one game.
+type Role string
+
+const (
+	RoleModerator Role = "moderator"
+	RolePlayer    Role = "player"
+	RoleSpectator Role = "spectator"
+	// RoleNone is the role of a player that is not part of the game.
+	RoleNone Role = ""
+)
+
+var anyGameRole = []Role{RoleModerator, RolePlayer, RoleSpectator}
+
+// commandRoles lists the roles allowed to send each game scoped command.
+// Commands that are not listed do not target a game and need no role.
+var commandRoles = map[string][]Role{
+	"set_text":              {RoleModerator},
+	"start_round":           {RoleModerator},
+	"end_round":             {RoleModerator},
+	"reveal_round":          {RoleModerator},
+	"set_choice_options":    {RoleModerator},
+	"start_voting":          {RoleModerator},
+	"end_voting":            {RoleModerator},
+	"vote":                  {RolePlayer},
+	"open_buzzer":           {RoleModerator},
+	"accept_buzz":           {RoleModerator},
+	"reject_buzz":           {RoleModerator},
+	"reset_buzzer":          {RoleModerator},
+	"buzz":                  {RolePlayer},
+	"create_team":           {RoleModerator},
+	"delete_team":           {RoleModerator},
+	"assign_player":         {RoleModerator},
+	"set_team_captain":      {RoleModerator},
+	"shuffle_teams":         {RoleModerator},
+	"set_answer_mode":       {RoleModerator},
+	"get_team_leaderboard":  anyGameRole,
+	"set_answer_visible":    {RoleModerator},
+	"set_answer_invisible":  {RoleModerator},
+	"delete_answer":         {RoleModerator},
+	"delete_game":           {RoleModerator},
+	"set_game_privacy":      {RoleModerator},
+	"set_anonymous_answers": {RoleModerator},
+	"set_presence_policy":   {RoleModerator},
+	"go_next_round":         {RoleModerator},
+	"attach_question_set":   {RoleModerator},
+	"set_answer_points":     {RoleModerator},
+	"set_answer_verdict":    {RoleModerator},
+	"set_accepted_answers":  {RoleModerator},
+	"set_answer":            {RolePlayer},
+	"choose":                {RolePlayer},
+	"leave_game":            {RolePlayer},
+	"get_text":              anyGameRole,
+	"get_rounds":            anyGameRole,
+	"get_game":              anyGameRole,
+	"get_connected_players": anyGameRole,
+	"get_leaderboard":       anyGameRole,
+	"get_presence":          anyGameRole,
+	"get_answers":           anyGameRole,
+	"subscribe":             anyGameRole,
+}
+
+// RoleOf returns the role a player has in game. Spectators are told apart by
+// their connection, see RoleIn.
+func RoleOf(game Game, playerId string) Role {
+	if game.ModeratorUUID == playerId {
+		return RoleModerator
+	}
+
+	if slices.Contains(game.Players, playerId) {
+		return RolePlayer
+	}
+
+	return RoleNone
+}
+
+// RoleIn returns the role c has in game. Connections watching the game
+// without a role in it are spectators, whether they have an identity or not.
+func (c *Connection) RoleIn(game Game) (Role, error) {
+	role := RoleNone
+
+	if c.PlayerID != nil {
+		player, err := c.GetPlayer()
+		if err != nil {
+			return RoleNone, err
+		}
+
+		role = RoleOf(game, player.ID)
+	}
+
+	if room, ok := c.rooms[game.ID]; role == RoleNone && ok && room.Spectators[c] {
+		return RoleSpectator, nil
+	}
+
+	if role == RoleNone && c.PlayerID == nil {
+		return RoleNone, ErrNotIdentified
+	}
+
+	return role, nil
+}
+
+// Authorize rejects msg unless its sender has one of the roles the command
+// requires in the game it targets, and returns that game. Commands that do
+// not target a game return none.
+func (c *Connection) Authorize(msg SocketMessage) (*Game, error) {
+	roles, ok := commandRoles[msg.Type]
+	if !ok {
+		return nil, nil
+	}
+
+	game, err := c.CommandGame(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := c.RoleIn(*game)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(roles, role) {
+		return game, nil
+	}
+
+	if slices.Equal(roles, []Role{RoleModerator}) {
+		return nil, ErrNotModerator
+	}
+
+	names := []string{}
+	for _, r := range roles {
+		names = append(names, string(r))
+	}
+
+	return nil, NewCommandError(ErrCodeForbidden, "%s requires one of the roles %s", msg.Type, strings.Join(names, ", "))
+}
+
+// CommandGame returns the game msg operates on: the game of the answer
+// named in the payload, the game named in the payload, or, for clients that
+// name none, the sender's active game.
+func (c *Connection) CommandGame(msg SocketMessage) (*Game, error) {
+	switch msg.Type {
+	case "delete_game", "go_next_round", "leave_game", "set_game_privacy", "set_anonymous_answers", "set_presence_policy",
+		"attach_question_set",
+		"create_team", "delete_team", "assign_player", "set_team_captain", "shuffle_teams":
+		var payload GameIDPayload
+		err := msg.Decode(&payload)
+		if err != nil {
+			return nil, InvalidPayload(err)
+		}
+
+		return c.hub.store.FindGameById(payload.GameID)
+	case "set_answer_visible", "set_answer_invisible", "delete_answer", "set_answer_points", "set_answer_verdict":
+		var payload AnswerIDPayload
+		err := msg.Decode(&payload)
+		if err != nil {
+			return nil, InvalidPayload(err)
+		}
+
+		answer, err := c.hub.store.FindAnswerById(payload.AnswerID)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.hub.store.FindGameById(answer.GameID)
+	}
+
+	// Any other command may name its game in a gameId field. Legacy
+	// payloads are strings and never do.
+	var scoped GameIDPayload
+	if msg.Version > 0 && json.Unmarshal(msg.Payload, &scoped) == nil && scoped.GameID != "" {
+		return c.hub.store.FindGameById(scoped.GameID)
+	}
+
+	return c.GetActiveGame()
+}
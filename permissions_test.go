@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// newPermissionsTestHub returns a hub with one game, moderated by "mod" and
+// played by "player", with one answer. "outsider" is not part of it.
+func newPermissionsTestHub(t *testing.T) *Hub {
+	hub := newTestHub(t)
+
+	hub.Do(func() {
+		for _, id := range []string{"mod", "player", "outsider"} {
+			hub.store.CreatePlayer(Player{ID: id, Nickname: id})
+		}
+
+		hub.store.CreateGame(Game{ID: "g1", Code: "ABC234", ModeratorUUID: "mod", Players: []string{"player"}})
+		hub.store.CreateRound(GameRound{ID: "r1", GameID: "g1", Round: 1, State: RoundDraft})
+		hub.store.CreateAnswer(Answer{ID: "a1", GameID: "g1", RoundID: "r1", PlayerID: "player"})
+	})
+
+	return hub
+}
+
+// roleConnections returns a connection of someone with each role in g1. The
+// spectator has no identity.
+func roleConnections(hub *Hub) map[Role]*Connection {
+	spectator := NewConnection(hub, nil, nil)
+
+	hub.Do(func() {
+		hub.JoinRoomAsSpectator(spectator, Game{ID: "g1"})
+	})
+
+	return map[Role]*Connection{
+		RoleModerator: NewConnection(hub, nil, &Session{PlayerID: "mod"}),
+		RolePlayer:    NewConnection(hub, nil, &Session{PlayerID: "player"}),
+		RoleSpectator: spectator,
+		RoleNone:      NewConnection(hub, nil, &Session{PlayerID: "outsider"}),
+	}
+}
+
+// authorize runs Authorize for a command aimed at g1 and its answer.
+func authorize(hub *Hub, c *Connection, command string) (*Game, error) {
+	msg := SocketMessage{
+		Version: ProtocolVersion,
+		Type:    command,
+		Payload: []byte(`{"gameId":"g1","answerId":"a1"}`),
+	}
+
+	var game *Game
+	var err error
+
+	hub.Do(func() {
+		game, err = c.Authorize(msg)
+	})
+
+	return game, err
+}
+
+func TestAuthorizeEnforcesCommandRoles(t *testing.T) {
+	hub := newPermissionsTestHub(t)
+	conns := roleConnections(hub)
+
+	for command, roles := range commandRoles {
+		for role, c := range conns {
+			name := string(role)
+			if role == RoleNone {
+				name = "none"
+			}
+
+			t.Run(command+"/"+name, func(t *testing.T) {
+				game, err := authorize(hub, c, command)
+
+				if slices.Contains(roles, role) {
+					if err != nil {
+						t.Fatalf("rejected: %s", err)
+					}
+
+					if game == nil || game.ID != "g1" {
+						t.Fatalf("authorized for game %+v, want g1", game)
+					}
+
+					return
+				}
+
+				want := ErrCodeForbidden
+				if slices.Equal(roles, []Role{RoleModerator}) {
+					want = ErrCodeNotModerator
+				}
+
+				var cmdErr *CommandError
+				if !errors.As(err, &cmdErr) || cmdErr.Code != want {
+					t.Fatalf("got %v, want %s", err, want)
+				}
+			})
+		}
+	}
+}
+
+func TestCommandRoles(t *testing.T) {
+	tests := []struct {
+		command string
+		allowed []Role
+	}{
+		{"set_text", []Role{RoleModerator}},
+		{"start_round", []Role{RoleModerator}},
+		{"set_answer_visible", []Role{RoleModerator}},
+		{"delete_game", []Role{RoleModerator}},
+		{"set_answer", []Role{RolePlayer}},
+		{"vote", []Role{RolePlayer}},
+		{"buzz", []Role{RolePlayer}},
+		{"get_answers", []Role{RoleModerator, RolePlayer, RoleSpectator}},
+		{"get_leaderboard", []Role{RoleModerator, RolePlayer, RoleSpectator}},
+		{"say_hello", []Role{RoleModerator, RolePlayer, RoleSpectator, RoleNone}},
+		{"join_game", []Role{RoleModerator, RolePlayer, RoleSpectator, RoleNone}},
+		{"create_game", []Role{RoleModerator, RolePlayer, RoleSpectator, RoleNone}},
+	}
+
+	hub := newPermissionsTestHub(t)
+	conns := roleConnections(hub)
+
+	for _, tt := range tests {
+		for role, c := range conns {
+			_, err := authorize(hub, c, tt.command)
+
+			if allowed := slices.Contains(tt.allowed, role); allowed != (err == nil) {
+				t.Errorf("%s as %q: allowed %v, got %v", tt.command, role, allowed, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"time"
+)
+
+// PresenceState says whether a player is around. Players are away while
+// they may still come back from a dropped connection, and gone once the
+// grace period for that has passed.
+type PresenceState string
+
+const (
+	PresenceOnline PresenceState = "online"
+	PresenceAway   PresenceState = "away"
+	PresenceGone   PresenceState = "gone"
+)
+
+type PlayerPresence struct {
+	PlayerID string        `json:"playerId"`
+	Nickname string        `json:"nickname"`
+	State    PresenceState `json:"state"`
+	Since    time.Time     `json:"since"`
+}
+
+type PresencePolicyPayload struct {
+	GameID string `json:"gameId"`
+	// RemoveGoneAfter is the number of seconds after which gone players are
+	// removed from the game. 0 keeps them.
+	RemoveGoneAfter int `json:"removeGoneAfter"`
+}
+
+// playerPresence tracks one player, together with the timers that move
+// them on while they are away or gone.
+type playerPresence struct {
+	state    PresenceState
+	since    time.Time
+	grace    Timer
+	removals map[string]Timer
+}
+
+func (p *playerPresence) stop() {
+	if p.grace != nil {
+		p.grace.Stop()
+		p.grace = nil
+	}
+
+	for gameID, t := range p.removals {
+		t.Stop()
+		delete(p.removals, gameID)
+	}
+}
+
+// PresenceOf returns the state of a player. Players the hub has not seen
+// since it started are gone.
+func (h *Hub) PresenceOf(playerId string) (PresenceState, time.Time) {
+	p, ok := h.presence[playerId]
+	if !ok {
+		return PresenceGone, time.Time{}
+	}
+
+	return p.state, p.since
+}
+
+// playerGames returns the games playerId plays or moderates, each once.
+func (h *Hub) playerGames(playerId string) ([]Game, error) {
+	games, err := h.store.FindGamesByPlayerId(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	moderated, err := h.store.FindGamesByModeratorId(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, game := range moderated {
+		if !slices.ContainsFunc(games, func(g Game) bool { return g.ID == game.ID }) {
+			games = append(games, game)
+		}
+	}
+
+	return games, nil
+}
+
+// setPresence moves player to state and sends the games they are part of
+// their presence. Online and away players are also announced with the older
+// player_connected and player_disconnected messages.
+func (h *Hub) setPresence(player Player, state PresenceState) *playerPresence {
+	p, ok := h.presence[player.ID]
+	if !ok {
+		p = &playerPresence{removals: map[string]Timer{}}
+		h.presence[player.ID] = p
+	}
+
+	p.stop()
+	p.state = state
+	p.since = h.clock.Now()
+
+	games, err := h.playerGames(player.ID)
+	if err != nil {
+		log.Println("find player games:", err)
+		return p
+	}
+
+	legacy := map[PresenceState]string{
+		PresenceOnline: "player_connected",
+		PresenceAway:   "player_disconnected",
+	}
+
+	for _, game := range games {
+		h.BroadcastPresence(game.ID)
+
+		if kind, ok := legacy[state]; ok {
+			msg, err := NewMessage(kind, player)
+			if err != nil {
+				log.Println("build "+kind+":", err)
+				return p
+			}
+
+			h.Broadcast(game.ID, msg)
+		}
+	}
+
+	return p
+}
+
+// PlayerOnline is called when a connection identifies as player.
+func (h *Hub) PlayerOnline(player Player) {
+	if state, _ := h.PresenceOf(player.ID); state == PresenceOnline {
+		return
+	}
+
+	h.setPresence(player, PresenceOnline)
+}
+
+// PlayerAway is called when the last connection of player is gone. Unless
+// they come back within the grace period, they are gone afterwards.
+func (h *Hub) PlayerAway(player Player) {
+	p := h.setPresence(player, PresenceAway)
+
+	p.grace = h.clock.AfterFunc(h.config.PresenceGracePeriod, func() {
+		h.commands <- func() {
+			if h.presence[player.ID] == p && p.state == PresenceAway {
+				h.playerGone(player)
+			}
+		}
+	})
+}
+
+func (h *Hub) playerGone(player Player) {
+	p := h.setPresence(player, PresenceGone)
+
+	games, err := h.store.FindGamesByPlayerId(player.ID)
+	if err != nil {
+		log.Println("find player games:", err)
+		return
+	}
+
+	for _, game := range games {
+		h.scheduleRemoval(p, player.ID, game)
+	}
+}
+
+// scheduleRemoval removes a gone player from game once the game's timeout
+// has passed, if it has one.
+func (h *Hub) scheduleRemoval(p *playerPresence, playerId string, game Game) {
+	if game.RemoveGoneAfter <= 0 {
+		return
+	}
+
+	if t, ok := p.removals[game.ID]; ok {
+		t.Stop()
+	}
+
+	after := time.Duration(game.RemoveGoneAfter) * time.Second
+
+	p.removals[game.ID] = h.clock.AfterFunc(after, func() {
+		h.commands <- func() {
+			if h.presence[playerId] != p || p.state != PresenceGone {
+				return
+			}
+
+			delete(p.removals, game.ID)
+			h.removeGonePlayer(game.ID, playerId)
+		}
+	})
+}
+
+func (h *Hub) removeGonePlayer(gameID string, playerId string) {
+	game, err := h.store.FindGameById(gameID)
+	if err != nil {
+		log.Println("find game:", err)
+		return
+	}
+
+	if RoleOf(*game, playerId) != RolePlayer {
+		return
+	}
+
+	err = h.RemovePlayer(game, playerId)
+	if err != nil {
+		log.Println("remove player:", err)
+		return
+	}
+
+	msg, err := NewMessage("leave_game", playerId)
+	if err != nil {
+		log.Println("build leave_game:", err)
+		return
+	}
+
+	h.Broadcast(gameID, msg)
+	h.BroadcastGames()
+}
+
+// RemovePlayer takes playerId out of game and its teams.
+func (h *Hub) RemovePlayer(game *Game, playerId string) error {
+	game.Players = slices.DeleteFunc(game.Players, func(p string) bool {
+		return p == playerId
+	})
+
+	if game.RemoveFromTeams(playerId) {
+		return h.store.UpdateGame(*game)
+	}
+
+	return h.store.UpdateGamePlayers(game.ID, game.Players)
+}
+
+// PresencePayload is who follows a game: the presence of the moderator and
+// every player, how many of the players are connected to it, and how many
+// spectators watch it. Players count once however many connections they
+// have, and the moderator is not counted.
+type PresencePayload struct {
+	GameID     string           `json:"gameId"`
+	Players    []PlayerPresence `json:"players"`
+	Connected  int              `json:"connected"`
+	Spectators int              `json:"spectators"`
+}
+
+func (h *Hub) PresenceMessage(gameID string) (*Message, error) {
+	game, err := h.store.FindGameById(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	players, err := h.store.FindPlayersByIds(append([]string{game.ModeratorUUID}, game.Players...))
+	if err != nil {
+		return nil, err
+	}
+
+	presence := PresencePayload{GameID: gameID, Players: []PlayerPresence{}}
+
+	for _, player := range players {
+		state, since := h.PresenceOf(player.ID)
+
+		presence.Players = append(presence.Players, PlayerPresence{
+			PlayerID: player.ID,
+			Nickname: player.Nickname,
+			State:    state,
+			Since:    since,
+		})
+	}
+
+	if room, ok := h.rooms[gameID]; ok {
+		connected := map[string]bool{}
+		for conn := range room.Members {
+			if conn.PlayerID != nil && *conn.PlayerID != game.ModeratorUUID {
+				connected[*conn.PlayerID] = true
+			}
+		}
+
+		presence.Connected = len(connected)
+		presence.Spectators = len(room.Spectators)
+	}
+
+	return NewMessage("presence", presence)
+}
+
+func (h *Hub) BroadcastPresence(gameID string) {
+	h.broadcastMessage(gameID, "presence", h.PresenceMessage)
+}
+
+func (c *Connection) SendPresence() error {
+	game, err := c.GetActiveGame()
+	if err != nil {
+		return err
+	}
+
+	msg, err := c.hub.PresenceMessage(game.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.Send(msg)
+}
+
+func (c *Connection) SetPresencePolicy(msg SocketMessage) (*Game, error) {
+	var payload PresencePolicyPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	if payload.RemoveGoneAfter < 0 {
+		return nil, NewCommandError(ErrCodeInvalidPayload, "removeGoneAfter must not be negative")
+	}
+
+	game, err := c.hub.store.FindGameById(payload.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	game.RemoveGoneAfter = payload.RemoveGoneAfter
+
+	err = c.hub.store.UpdateGame(*game)
+	if err != nil {
+		return nil, err
+	}
+
+	// Players that are already gone get the new timeout from now on.
+	for _, playerId := range game.Players {
+		p, ok := c.hub.presence[playerId]
+		if !ok || p.state != PresenceGone {
+			continue
+		}
+
+		if t, ok := p.removals[game.ID]; ok {
+			t.Stop()
+			delete(p.removals, game.ID)
+		}
+
+		c.hub.scheduleRemoval(p, playerId, *game)
+	}
+
+	return game, nil
+}
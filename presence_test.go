@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPresenceCountsPlayersOnce(t *testing.T) {
+	hub := newTestHub(t)
+
+	mod := connect(t, hub, nil)
+	defer mod.close()
+	modID := mod.mustHello("Mod")
+
+	var game Game
+	mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &game)
+
+	player := connect(t, hub, nil)
+	defer player.close()
+	playerID := player.mustHello("Player")
+	player.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+
+	// A second tab of each of them.
+	for _, id := range []string{modID, playerID} {
+		tab := connect(t, hub, &Session{PlayerID: id})
+		defer tab.close()
+		tab.mustRequest("subscribe", GameIDPayload{GameID: game.ID}, nil)
+	}
+
+	spectator := connect(t, hub, nil)
+	defer spectator.close()
+	spectator.mustRequest("spectate", JoinGamePayload{Code: game.Code}, nil)
+
+	mod.mustRequest("get_presence", GameIDPayload{GameID: game.ID}, nil)
+
+	msg, ok := mod.last("presence")
+	if !ok {
+		t.Fatal("got no presence")
+	}
+
+	var presence PresencePayload
+	err := json.Unmarshal(msg.Payload, &presence)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if presence.Connected != 1 || presence.Spectators != 1 || len(presence.Players) != 2 {
+		t.Fatalf("presence %+v, want 1 connected player of 2 and 1 spectator", presence)
+	}
+
+	for _, p := range presence.Players {
+		if p.State != PresenceOnline {
+			t.Errorf("%s is %s, want online", p.Nickname, p.State)
+		}
+	}
+}
+
+type presenceGame struct {
+	hub      *Hub
+	clock    *manualClock
+	mod      *testClient
+	player   *testClient
+	playerID string
+	game     Game
+}
+
+// startPresenceGame has a player join a moderator's game on a manual clock.
+func startPresenceGame(t *testing.T) *presenceGame {
+	hub := newTestHub(t)
+	g := &presenceGame{hub: hub, clock: useManualClock(hub)}
+
+	g.mod = connect(t, hub, nil)
+	t.Cleanup(g.mod.close)
+	g.mod.mustHello("Mod")
+	g.mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &g.game)
+
+	g.player = connect(t, hub, nil)
+	t.Cleanup(g.player.close)
+	g.playerID = g.player.mustHello("Player")
+	g.player.mustRequest("join_game", JoinGamePayload{GameID: g.game.ID}, nil)
+	g.mod.mustRequest("get_presence", GameIDPayload{GameID: g.game.ID}, nil)
+
+	return g
+}
+
+// disconnect closes the player's connection and waits until the hub let
+// go of it.
+func (g *presenceGame) disconnect(t *testing.T) {
+	g.player.close()
+	waitForConnections(t, g.hub, 1)
+}
+
+// reconnect connects the player again with their session.
+func (g *presenceGame) reconnect(t *testing.T) {
+	g.player = connect(t, g.hub, &Session{PlayerID: g.playerID})
+	t.Cleanup(g.player.close)
+	g.player.mustHello("Player")
+}
+
+func (g *presenceGame) advance(d time.Duration) {
+	advance(g.hub, g.clock, d)
+}
+
+// state returns the player's state as the hub and the moderator see it.
+func (g *presenceGame) state(t *testing.T) PresenceState {
+	t.Helper()
+
+	var state PresenceState
+	g.hub.Do(func() {
+		state, _ = g.hub.PresenceOf(g.playerID)
+	})
+
+	// Replies come after the broadcasts queued before them, so the last
+	// presence the moderator got is the current one.
+	g.mod.mustRequest("get_text", GameIDPayload{GameID: g.game.ID}, nil)
+
+	msg, ok := g.mod.last("presence")
+	if !ok {
+		t.Fatal("the moderator got no presence")
+	}
+
+	var presence PresencePayload
+	err := json.Unmarshal(msg.Payload, &presence)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range presence.Players {
+		if p.PlayerID == g.playerID && p.State != state {
+			t.Fatalf("the moderator sees the player %s, the hub %s", p.State, state)
+		}
+	}
+
+	return state
+}
+
+// players returns the players of the game as stored.
+func (g *presenceGame) players(t *testing.T) []string {
+	t.Helper()
+
+	var game *Game
+	var err error
+	g.hub.Do(func() {
+		game, err = g.hub.store.FindGameById(g.game.ID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return game.Players
+}
+
+func TestPresenceAwayThenGone(t *testing.T) {
+	g := startPresenceGame(t)
+	grace := g.hub.config.PresenceGracePeriod
+
+	if state := g.state(t); state != PresenceOnline {
+		t.Fatalf("player is %s, want online", state)
+	}
+
+	g.disconnect(t)
+
+	if state := g.state(t); state != PresenceAway {
+		t.Fatalf("player is %s after disconnecting, want away", state)
+	}
+
+	g.advance(grace - time.Second)
+
+	if state := g.state(t); state != PresenceAway {
+		t.Fatalf("player is %s before the grace period is over, want away", state)
+	}
+
+	g.advance(time.Second)
+
+	if state := g.state(t); state != PresenceGone {
+		t.Fatalf("player is %s after the grace period, want gone", state)
+	}
+
+	// Without a policy gone players stay in the game.
+	g.advance(24 * time.Hour)
+
+	if players := g.players(t); !slices.Equal(players, []string{g.playerID}) {
+		t.Fatalf("players %v, want the gone player kept", players)
+	}
+}
+
+func TestPresenceReconnectWithinGracePeriod(t *testing.T) {
+	g := startPresenceGame(t)
+	grace := g.hub.config.PresenceGracePeriod
+
+	g.disconnect(t)
+	g.advance(grace / 2)
+	g.reconnect(t)
+
+	if state := g.state(t); state != PresenceOnline {
+		t.Fatalf("player is %s after reconnecting, want online", state)
+	}
+
+	// The grace timer of the old connection must not fire any more.
+	g.advance(grace)
+
+	if state := g.state(t); state != PresenceOnline {
+		t.Fatalf("player is %s after the old grace period, want online", state)
+	}
+}
+
+func TestGonePlayersAreRemoved(t *testing.T) {
+	g := startPresenceGame(t)
+	grace := g.hub.config.PresenceGracePeriod
+
+	g.mod.mustRequest("set_presence_policy", PresencePolicyPayload{GameID: g.game.ID, RemoveGoneAfter: 60}, nil)
+
+	g.disconnect(t)
+	g.advance(grace)
+	g.advance(59 * time.Second)
+
+	if players := g.players(t); len(players) != 1 {
+		t.Fatalf("players %v, want the player kept before the timeout", players)
+	}
+
+	if _, ok := g.mod.last("leave_game"); ok {
+		t.Fatal("leave_game before the timeout")
+	}
+
+	g.advance(time.Second)
+
+	if players := g.players(t); len(players) != 0 {
+		t.Fatalf("players %v, want the gone player removed", players)
+	}
+
+	g.mod.mustRequest("get_text", GameIDPayload{GameID: g.game.ID}, nil)
+
+	msg, ok := g.mod.last("leave_game")
+	if !ok {
+		t.Fatal("the moderator got no leave_game")
+	}
+
+	var left string
+	err := json.Unmarshal(msg.Payload, &left)
+	if err != nil || left != g.playerID {
+		t.Fatalf("leave_game for %q (%v), want %s", left, err, g.playerID)
+	}
+}
+
+func TestPresencePolicyReschedulesRemoval(t *testing.T) {
+	g := startPresenceGame(t)
+	grace := g.hub.config.PresenceGracePeriod
+
+	g.mod.mustRequest("set_presence_policy", PresencePolicyPayload{GameID: g.game.ID, RemoveGoneAfter: 60}, nil)
+
+	g.disconnect(t)
+	g.advance(grace)
+	g.advance(40 * time.Second)
+
+	// The new timeout counts from when it is set, not from when the
+	// player went.
+	g.mod.mustRequest("set_presence_policy", PresencePolicyPayload{GameID: g.game.ID, RemoveGoneAfter: 30}, nil)
+	g.advance(29 * time.Second)
+
+	if players := g.players(t); len(players) != 1 {
+		t.Fatalf("players %v, want the player kept before the new timeout", players)
+	}
+
+	g.advance(time.Second)
+
+	if players := g.players(t); len(players) != 0 {
+		t.Fatalf("players %v, want the gone player removed", players)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ProtocolVersion is the current envelope version. Clients that send no
+// version speak the legacy protocol, where every payload is a string that
+// often contains JSON itself. They keep working, and replies to them are
+// encoded the same way.
+const ProtocolVersion = 1
+
+type legacySocketMessage struct {
+	Type      string `json:"type"`
+	RequestID string `json:"requestId,omitempty"`
+	Payload   string `json:"payload"`
+}
+
+// legacyString is implemented by payloads that legacy clients send as a
+// bare string instead of an object.
+type legacyString interface {
+	setLegacy(s string)
+}
+
+// Decode unmarshals the payload into v, unwrapping legacy string payloads.
+func (m SocketMessage) Decode(v any) error {
+	payload := m.Payload
+
+	if len(payload) == 0 {
+		payload = json.RawMessage("null")
+	}
+
+	if m.Version == 0 {
+		var s string
+		if json.Unmarshal(payload, &s) == nil {
+			switch p := v.(type) {
+			case *string:
+				*p = s
+				return nil
+			case legacyString:
+				if !json.Valid([]byte(s)) || s == "" || s[0] != '{' {
+					p.setLegacy(s)
+					return nil
+				}
+			}
+
+			payload = []byte(s)
+		}
+	}
+
+	return json.Unmarshal(payload, v)
+}
+
+type AckPayload struct {
+	Command string `json:"command"`
+	Result  any    `json:"result,omitempty"`
+}
+
+type ErrorPayload struct {
+	Command string    `json:"command"`
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// Message is an outbound message. The payload is marshalled once and the
+// envelope is encoded at most once per protocol version, so broadcasting it
+// to a whole room costs the same as sending it to one client. Messages are
+// only used on the hub goroutine.
+type Message struct {
+	Type      string
+	RequestID string
+	GameID    string
+	Seq       uint64
+	Payload   json.RawMessage
+
+	legacy  []byte
+	current []byte
+}
+
+func NewMessage(kind string, payload any) (*Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Type:    kind,
+		Payload: data,
+	}, nil
+}
+
+// Encode returns the wire form of the message for a client speaking the
+// given protocol version.
+func (m *Message) Encode(version int) ([]byte, error) {
+	if version == 0 {
+		if m.legacy == nil {
+			data, err := json.Marshal(legacySocketMessage{
+				Type:      m.Type,
+				RequestID: m.RequestID,
+				Payload:   legacyPayload(m.Payload),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			m.legacy = data
+		}
+
+		return m.legacy, nil
+	}
+
+	if version > ProtocolVersion {
+		return nil, fmt.Errorf("unsupported protocol version %d", version)
+	}
+
+	if m.current == nil {
+		data, err := json.Marshal(SocketMessage{
+			Version:   ProtocolVersion,
+			Type:      m.Type,
+			RequestID: m.RequestID,
+			GameID:    m.GameID,
+			Seq:       m.Seq,
+			Payload:   m.Payload,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		m.current = data
+	}
+
+	return m.current, nil
+}
+
+// legacyPayload turns a JSON payload into the string legacy clients expect:
+// strings are sent as they are, everything else as its JSON text.
+func legacyPayload(payload json.RawMessage) string {
+	var s string
+	if json.Unmarshal(payload, &s) == nil {
+		return s
+	}
+
+	return string(payload)
+}
+
+type GameIDPayload struct {
+	GameID string `json:"gameId"`
+}
+
+func (p *GameIDPayload) setLegacy(s string) {
+	p.GameID = s
+}
+
+// JoinGamePayload names the game to join either by ID or by join code.
+type JoinGamePayload struct {
+	GameID   string `json:"gameId"`
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+func (p *JoinGamePayload) setLegacy(s string) {
+	p.GameID = s
+}
+
+// SetGamePrivacyPayload changes who can find and join a game. The password
+// is only changed when it is given; an empty one removes it.
+type SetGamePrivacyPayload struct {
+	GameID   string  `json:"gameId"`
+	Private  bool    `json:"private"`
+	Password *string `json:"password"`
+}
+
+type AnswerIDPayload struct {
+	AnswerID string `json:"answerId"`
+}
+
+func (p *AnswerIDPayload) setLegacy(s string) {
+	p.AnswerID = s
+}
+
+// AnswerPointsPayload sets the points of an answer, or changes them by
+// Delta when Points is left out.
+type AnswerPointsPayload struct {
+	AnswerID string `json:"answerId"`
+	Points   *int   `json:"points"`
+	Delta    int    `json:"delta"`
+}
+
+// StartRoundPayload optionally limits the round to Duration seconds.
+type StartRoundPayload struct {
+	Duration int `json:"duration"`
+}
+
+func (p *StartRoundPayload) setLegacy(s string) {
+	p.Duration, _ = strconv.Atoi(s)
+}
+
+// AnswerKeyPayload sets the accepted answers of a round. Settings that are
+// left out take the server's defaults.
+type AnswerKeyPayload struct {
+	Answers        []AcceptedAnswer `json:"answers"`
+	Tolerance      *int             `json:"tolerance"`
+	CloseTolerance *int             `json:"closeTolerance"`
+	CorrectPoints  *int             `json:"correctPoints"`
+	ClosePoints    *int             `json:"closePoints"`
+}
+
+type AnswerVerdictPayload struct {
+	AnswerID string  `json:"answerId"`
+	Verdict  Verdict `json:"verdict"`
+}
+
+type TextPayload struct {
+	Text string `json:"text"`
+}
+
+func (p *TextPayload) setLegacy(s string) {
+	p.Text = s
+}
+
+type CreateGamePayload struct {
+	Name     string `json:"name"`
+	Private  bool   `json:"private"`
+	Password string `json:"password"`
+}
+
+func (p *CreateGamePayload) setLegacy(s string) {
+	p.Name = s
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+)
+
+// questionCSVHeader is the header row of exported question sets. Imports
+// accept the columns in any order, only text is required.
+var questionCSVHeader = []string{"text", "answer", "notes"}
+
+// QuestionSetPayload is the JSON import and export format of a question set.
+type QuestionSetPayload struct {
+	Name      string     `json:"name"`
+	Questions []Question `json:"questions"`
+}
+
+type AttachQuestionSetPayload struct {
+	GameID        string `json:"gameId"`
+	QuestionSetID string `json:"questionSetId"`
+}
+
+// Validate checks an imported set before it is stored.
+func (p QuestionSetPayload) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return NewCommandError(ErrCodeInvalidPayload, "question set needs a name")
+	}
+
+	if len(p.Questions) == 0 {
+		return NewCommandError(ErrCodeInvalidPayload, "question set has no questions")
+	}
+
+	for i, q := range p.Questions {
+		if strings.TrimSpace(q.Text) == "" {
+			return NewCommandError(ErrCodeInvalidPayload, "question %d has no text", i+1)
+		}
+	}
+
+	return nil
+}
+
+// ParseQuestionsCSV reads questions from CSV with a header row.
+func ParseQuestionsCSV(r io.Reader) ([]Question, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, NewCommandError(ErrCodeInvalidPayload, "empty CSV")
+	}
+
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["text"]; !ok {
+		return nil, NewCommandError(ErrCodeInvalidPayload, "CSV has no text column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	questions := []Question{}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, InvalidPayload(err)
+		}
+
+		if slices.IndexFunc(record, func(s string) bool { return strings.TrimSpace(s) != "" }) < 0 {
+			continue
+		}
+
+		questions = append(questions, Question{
+			Text:   field(record, "text"),
+			Answer: field(record, "answer"),
+			Notes:  field(record, "notes"),
+		})
+	}
+
+	return questions, nil
+}
+
+func WriteQuestionsCSV(w io.Writer, questions []Question) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write(questionCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, q := range questions {
+		err = writer.Write([]string{q.Text, q.Answer, q.Notes})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// LoadQuestion puts the next question from the game's attached set into
+// round, along with its reference answer as the accepted answer, and moves the
+// game's cursor past it. Nothing changes when there is no set or it ran out of
+// questions.
+func (h *Hub) LoadQuestion(game *Game, round *GameRound) error {
+	if game.QuestionSetID == "" {
+		return nil
+	}
+
+	set, err := h.store.FindQuestionSetById(game.QuestionSetID)
+	if errors.Is(err, ErrQuestionSetNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if game.QuestionIndex < 0 || game.QuestionIndex >= len(set.Questions) {
+		return nil
+	}
+
+	question := set.Questions[game.QuestionIndex]
+	round.Question = question.Text
+
+	if question.Answer != "" {
+		round.Key = h.NewAnswerKey([]AcceptedAnswer{{Text: question.Answer}})
+	}
+
+	game.QuestionIndex++
+
+	return h.store.UpdateGame(*game)
+}
+
+// AttachQuestionSet makes the game take its questions from a set of the
+// moderator, or detaches the current set when no set is given. Attaching a set
+// starts over at its first question. A round that is still a draft without a
+// question gets its question right away.
+func (c *Connection) AttachQuestionSet(msg SocketMessage) (*Game, error) {
+	var payload AttachQuestionSetPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	game, err := c.hub.store.FindGameById(payload.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.QuestionSetID != "" {
+		set, err := c.hub.store.FindQuestionSetById(payload.QuestionSetID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Other people's sets are not even acknowledged to exist.
+		if set.OwnerID != game.ModeratorUUID {
+			return nil, ErrQuestionSetNotFound
+		}
+	}
+
+	game.QuestionSetID = payload.QuestionSetID
+	game.QuestionIndex = 0
+
+	err = c.hub.store.UpdateGame(*game)
+	if err != nil {
+		return nil, err
+	}
+
+	round, err := c.hub.store.FindActiveRoundByGameId(game.ID)
+	if err == nil && round.State == RoundDraft && round.Question == "" {
+		err = c.hub.LoadQuestion(game, round)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.hub.store.UpdateRound(*round)
+		if err != nil {
+			return nil, err
+		}
+
+		c.hub.BroadcastText(game.ID)
+		c.hub.BroadcastRounds(game.ID)
+	}
+
+	return game, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+// Questions come from the game's cursor into the set, so a set attached in a
+// later round still starts at its first question.
+func TestQuestionSetCursor(t *testing.T) {
+	hub := newTestHub(t)
+
+	mod := connect(t, hub, nil)
+	defer mod.close()
+	modID := mod.mustHello("Mod")
+
+	var game Game
+	mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &game)
+
+	hub.Do(func() {
+		hub.store.CreateQuestionSet(QuestionSet{ID: "set", OwnerID: modID, Questions: []Question{
+			{Text: "First?"}, {Text: "Second?"}, {Text: "Third?"},
+		}})
+	})
+
+	question := func() string {
+		var round *GameRound
+		var err error
+
+		hub.Do(func() {
+			round, err = hub.store.FindActiveRoundByGameId(game.ID)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return round.Question
+	}
+
+	nextRound := func() {
+		mod.mustRequest("go_next_round", GameIDPayload{GameID: game.ID}, nil)
+	}
+
+	nextRound()
+	nextRound()
+
+	attach := AttachQuestionSetPayload{GameID: game.ID, QuestionSetID: "set"}
+	mod.mustRequest("attach_question_set", attach, &game)
+
+	if got := question(); got != "First?" || game.QuestionIndex != 1 {
+		t.Fatalf("round 3 got %q at index %d, want the first question", got, game.QuestionIndex)
+	}
+
+	nextRound()
+
+	if got := question(); got != "Second?" {
+		t.Fatalf("round 4 got %q, want the second question", got)
+	}
+
+	// Attaching again starts over; the current round keeps its question.
+	mod.mustRequest("attach_question_set", attach, &game)
+
+	if got := question(); got != "Second?" || game.QuestionIndex != 0 {
+		t.Fatalf("reattaching changed the round to %q at index %d", got, game.QuestionIndex)
+	}
+
+	nextRound()
+
+	if got := question(); got != "First?" {
+		t.Fatalf("round after reattaching got %q, want the first question", got)
+	}
+
+	for range 3 {
+		nextRound()
+	}
+
+	if got := question(); got != "" {
+		t.Fatalf("round after the set ran out got %q", got)
+	}
+}
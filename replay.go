@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+)
+
+// Every event broadcast to a game carries the game ID and a sequence number
+// in its envelope. Clients remember the last number they saw and present it
+// when they say hello again after a reconnect: they get the events they
+// missed, or one snapshot if those are no longer kept.
+//
+// Sequence numbers only ever grow, but coalesced messages leave gaps, so
+// clients should not expect them to be consecutive. Rooms start numbering
+// at the current time in microseconds, so numbers keep growing across
+// restarts and a client never mistakes events from before a restart for
+// new ones.
+
+// roomEvent is a broadcast kept for replay. Events whose payload depends on
+// the recipient, like the answers, keep one message per viewer instead.
+type roomEvent struct {
+	msg   *Message
+	views map[string]*Message
+}
+
+// For returns the message viewerId got for the event. Viewers that were not
+// around when it was sent get the view of somebody without a role.
+func (e roomEvent) For(viewerId string) *Message {
+	if e.views == nil {
+		return e.msg
+	}
+
+	if msg, ok := e.views[viewerId]; ok {
+		return msg
+	}
+
+	return e.views[""]
+}
+
+func (e roomEvent) Seq() uint64 {
+	if e.views == nil {
+		return e.msg.Seq
+	}
+
+	return e.views[""].Seq
+}
+
+func (h *Hub) firstSeq() uint64 {
+	return uint64(h.clock.Now().UnixMicro())
+}
+
+// record numbers the event and adds it to the history.
+func (r *Room) record(e roomEvent) {
+	r.seq++
+
+	if e.views == nil {
+		e.msg.GameID = r.GameID
+		e.msg.Seq = r.seq
+	}
+
+	for _, msg := range e.views {
+		msg.GameID = r.GameID
+		msg.Seq = r.seq
+	}
+
+	r.history = append(r.history, e)
+
+	if len(r.history) > r.historySize {
+		r.history = r.history[len(r.history)-r.historySize:]
+	}
+}
+
+// BroadcastViews sends every member the view keyed by their player ID and
+// every spectator the view keyed by "", which must be present.
+func (r *Room) BroadcastViews(views map[string]*Message) {
+	r.record(roomEvent{views: views})
+
+	for conn := range r.Members {
+		if conn.PlayerID == nil {
+			continue
+		}
+
+		msg, ok := views[*conn.PlayerID]
+		if !ok {
+			msg = views[""]
+		}
+
+		err := conn.Send(msg)
+		if err != nil {
+			log.Println("write:", err)
+		}
+	}
+
+	r.BroadcastSpectators(views[""])
+}
+
+// Since returns the events after seq. It reports false if some of them are
+// no longer kept, or if seq was never handed out.
+func (r *Room) Since(seq uint64) ([]roomEvent, bool) {
+	if seq > r.seq {
+		return nil, false
+	}
+
+	if seq == r.seq {
+		return nil, true
+	}
+
+	if len(r.history) == 0 || r.history[0].Seq() > seq+1 {
+		return nil, false
+	}
+
+	for i, e := range r.history {
+		if e.Seq() > seq {
+			return r.history[i:], true
+		}
+	}
+
+	return nil, true
+}
+
+// SnapshotPayload consolidates everything a client needs to catch up with
+// a game. Messages holds the payloads it would otherwise get one by one,
+// keyed by message type.
+type SnapshotPayload struct {
+	GameID   string                     `json:"gameId"`
+	Seq      uint64                     `json:"seq"`
+	Messages map[string]json.RawMessage `json:"messages"`
+}
+
+// SnapshotMessage builds a snapshot of game as playerId sees it.
+func (h *Hub) SnapshotMessage(game Game, playerId string) (*Message, error) {
+	room := h.Room(game.ID)
+
+	builders := []func() (*Message, error){
+		func() (*Message, error) { return NewMessage("get_game", game) },
+		func() (*Message, error) { return h.AnswersMessage(game.ID, playerId) },
+		func() (*Message, error) { return h.GamesMessage(playerId) },
+		func() (*Message, error) { return h.RoundsMessage(game.ID) },
+		func() (*Message, error) { return h.ConnectedPlayersMessage(game.ID) },
+		func() (*Message, error) { return h.TextMessage(game.ID) },
+		func() (*Message, error) { return h.LeaderboardMessage(game.ID) },
+		func() (*Message, error) { return h.PresenceMessage(game.ID) },
+	}
+
+	snapshot := SnapshotPayload{
+		GameID:   game.ID,
+		Seq:      room.seq,
+		Messages: map[string]json.RawMessage{},
+	}
+
+	for _, build := range builders {
+		msg, err := build()
+		if errors.Is(err, ErrRoundNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot.Messages[msg.Type] = msg.Payload
+	}
+
+	msg, err := NewMessage("snapshot", snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.GameID = game.ID
+	msg.Seq = room.seq
+
+	return msg, nil
+}
+
+// Resume replays the events of game the client missed since lastSeq. It
+// reports false if they are no longer kept and the client needs a snapshot
+// instead. Events that depend on the recipient are only replayed if the
+// client's player was among their viewers. Otherwise the client gets the
+// answers as it sees them now after the other events.
+func (c *Connection) Resume(game Game, lastSeq uint64) bool {
+	room, ok := c.rooms[game.ID]
+	if !ok {
+		return false
+	}
+
+	events, ok := room.Since(lastSeq)
+	if !ok {
+		return false
+	}
+
+	missedViews := false
+
+	for _, e := range events {
+		if _, ok := e.views[*c.PlayerID]; e.views != nil && !ok {
+			missedViews = true
+			continue
+		}
+
+		err := c.Send(e.For(*c.PlayerID))
+		if err != nil {
+			log.Println("write:", err)
+		}
+	}
+
+	if !missedViews {
+		return true
+	}
+
+	msg, err := c.hub.AnswersMessage(game.ID, *c.PlayerID)
+	if err != nil {
+		log.Println("build all_answers:", err)
+		return true
+	}
+
+	msg.GameID = game.ID
+	msg.Seq = room.seq
+
+	err = c.Send(msg)
+	if err != nil {
+		log.Println("write:", err)
+	}
+
+	return true
+}
+
+func (c *Connection) SendSnapshot(game Game) error {
+	msg, err := c.hub.SnapshotMessage(game, *c.PlayerID)
+	if err != nil {
+		log.Println("build snapshot:", err)
+		return err
+	}
+
+	return c.Send(msg)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// A player whose connections were all gone missed the answer views made for
+// them, and resumes with the answers as they see them now.
+func TestResumeRebuildsMissedAnswerViews(t *testing.T) {
+	hub := newTestHub(t)
+
+	mod := connect(t, hub, nil)
+	defer mod.close()
+	mod.mustHello("Mod")
+
+	var game Game
+	mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &game)
+	mod.mustRequest("start_round", GameIDPayload{GameID: game.ID}, nil)
+
+	player := connect(t, hub, nil)
+	playerID := player.mustHello("Player")
+	player.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+	player.mustRequest("set_answer", TextPayload{Text: "my own answer"}, nil)
+
+	var lastSeq uint64
+	for _, msg := range player.receive() {
+		lastSeq = max(lastSeq, msg.Seq)
+	}
+
+	player.close()
+	waitForConnections(t, hub, 1)
+
+	other := connect(t, hub, nil)
+	defer other.close()
+	other.mustHello("Other")
+	other.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+	other.mustRequest("set_answer", TextPayload{Text: "another answer"}, nil)
+
+	again := connect(t, hub, &Session{PlayerID: playerID})
+	defer again.close()
+	again.mustRequest("say_hello", SayHelloPayload{Name: "Player", GameID: game.ID, LastSeq: lastSeq}, nil)
+
+	if _, ok := again.last("snapshot"); ok {
+		t.Fatal("got a snapshot instead of the missed events")
+	}
+
+	answers, ok := again.last("all_answers")
+	if !ok {
+		t.Fatal("got no answers")
+	}
+
+	if !bytes.Contains(answers.Payload, []byte("my own answer")) {
+		t.Fatalf("resumed answers lack the player's own: %s", answers.Payload)
+	}
+
+	if bytes.Contains(answers.Payload, []byte("another answer")) {
+		t.Fatalf("resumed answers show somebody else's: %s", answers.Payload)
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"log"
+	"slices"
+)
+
+// Room groups the connections taking part in one game, so game updates only
+// go to the people playing it. Rooms are owned by the hub and must only be
+// touched from the hub goroutine.
+type Room struct {
+	GameID    string
+	Members   map[*Connection]bool
+	Moderator *Connection
+	// Spectators follow the game without taking part. They only get the
+	// message types in spectatorMessages.
+	Spectators map[*Connection]bool
+
+	// seq is the sequence number of the last event broadcast to the room,
+	// history the most recent events, kept for replay.
+	seq         uint64
+	history     []roomEvent
+	historySize int
+}
+
+// NewRoom creates a room whose events are numbered from seq on and which
+// keeps up to historySize of them for replay.
+func NewRoom(gameID string, seq uint64, historySize int) *Room {
+	return &Room{
+		GameID:      gameID,
+		Members:     map[*Connection]bool{},
+		Spectators:  map[*Connection]bool{},
+		seq:         seq,
+		historySize: historySize,
+	}
+}
+
+func (r *Room) Join(c *Connection, moderator bool) {
+	r.Members[c] = true
+
+	if moderator {
+		r.Moderator = c
+	}
+}
+
+func (r *Room) Leave(c *Connection) {
+	delete(r.Members, c)
+	delete(r.Spectators, c)
+
+	if r.Moderator != c {
+		return
+	}
+
+	// The moderator may still be connected elsewhere.
+	r.Moderator = nil
+
+	for conn := range r.Members {
+		if conn.PlayerID != nil && c.PlayerID != nil && *conn.PlayerID == *c.PlayerID {
+			r.Moderator = conn
+			return
+		}
+	}
+}
+
+// Broadcast queues the same message for every member, and for the
+// spectators if it is meant for them.
+func (r *Room) Broadcast(msg *Message) {
+	r.record(roomEvent{msg: msg})
+
+	for conn := range r.Members {
+		err := conn.Send(msg)
+		if err != nil {
+			log.Println("write:", err)
+		}
+	}
+
+	if spectatorMessages[msg.Type] {
+		r.BroadcastSpectators(msg)
+	}
+}
+
+func (r *Room) BroadcastSpectators(msg *Message) {
+	for conn := range r.Spectators {
+		err := conn.Send(msg)
+		if err != nil {
+			log.Println("write:", err)
+		}
+	}
+}
+
+// Room returns the room of a game, creating it on first use.
+func (h *Hub) Room(gameID string) *Room {
+	room, ok := h.rooms[gameID]
+	if !ok {
+		room = NewRoom(gameID, h.firstSeq(), h.config.ReplayBufferSize)
+		h.rooms[gameID] = room
+	}
+
+	return room
+}
+
+func (h *Hub) JoinRoom(c *Connection, game Game) {
+	moderator := c.PlayerID != nil && *c.PlayerID == game.ModeratorUUID
+	room := h.Room(game.ID)
+
+	room.Join(c, moderator)
+	c.rooms[game.ID] = room
+}
+
+func (h *Hub) LeaveRoom(c *Connection, gameID string) {
+	room, ok := c.rooms[gameID]
+	if !ok {
+		return
+	}
+
+	room.Leave(c)
+	delete(c.rooms, gameID)
+
+	h.BroadcastPresence(gameID)
+}
+
+func (h *Hub) LeaveAllRooms(c *Connection) {
+	for gameID := range c.rooms {
+		h.LeaveRoom(c, gameID)
+	}
+}
+
+// CloseRoom drops a deleted game's room and detaches all of its members.
+func (h *Hub) CloseRoom(gameID string) {
+	room, ok := h.rooms[gameID]
+	if !ok {
+		return
+	}
+
+	for conn := range room.Members {
+		delete(conn.rooms, gameID)
+	}
+
+	for conn := range room.Spectators {
+		delete(conn.rooms, gameID)
+	}
+
+	delete(h.rooms, gameID)
+}
+
+func (h *Hub) Broadcast(gameID string, msg *Message) {
+	room, ok := h.rooms[gameID]
+	if !ok {
+		return
+	}
+
+	room.Broadcast(msg)
+}
+
+// AnswersMessage lists the answers of the active round as viewerId may
+// see them.
+func (h *Hub) AnswersMessage(gameID string, viewerId string) (*Message, error) {
+	game, round, answers, err := h.activeAnswers(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMessage("all_answers", AnswersFor(*game, *round, answers, viewerId))
+}
+
+func (h *Hub) RoundsMessage(gameID string) (*Message, error) {
+	rounds, err := h.store.FindRoundsByGameId(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMessage("get_rounds", rounds)
+}
+
+func (h *Hub) TextMessage(gameID string) (*Message, error) {
+	round, err := h.store.FindActiveRoundByGameId(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMessage("set_text", round.Question)
+}
+
+func (h *Hub) ConnectedPlayersMessage(gameID string) (*Message, error) {
+	playerIds := []string{}
+
+	if room, ok := h.rooms[gameID]; ok {
+		for conn := range room.Members {
+			if conn.PlayerID != nil {
+				playerIds = append(playerIds, *conn.PlayerID)
+			}
+		}
+	}
+
+	players, err := h.store.FindPlayersByIds(playerIds)
+	if err != nil {
+		return nil, err
+	}
+
+	game, err := h.store.FindGameById(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range players {
+		players[i].TeamID = game.TeamIDOf(players[i].ID)
+	}
+
+	return NewMessage("get_connected_players", players)
+}
+
+// GamesMessage builds the game listing as playerId is allowed to see it.
+func (h *Hub) GamesMessage(playerId string) (*Message, error) {
+	games, err := h.store.FindAllGames()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMessage("get_games", VisibleGames(games, playerId))
+}
+
+// VisibleGames filters games down to the ones listed for playerId.
+func VisibleGames(games []Game, playerId string) []Game {
+	res := []Game{}
+
+	for _, g := range games {
+		if g.VisibleTo(playerId) {
+			res = append(res, g)
+		}
+	}
+
+	return res
+}
+
+func (h *Hub) broadcastMessage(gameID string, kind string, build func(string) (*Message, error)) {
+	msg, err := build(gameID)
+	if err != nil {
+		log.Printf("build %s: %s", kind, err)
+		return
+	}
+
+	h.Broadcast(gameID, msg)
+}
+
+// BroadcastAnswers sends every member the answers as they may see them.
+// Spectators all get the view of somebody without a role in the game.
+func (h *Hub) BroadcastAnswers(gameID string) {
+	room, ok := h.rooms[gameID]
+	if !ok {
+		return
+	}
+
+	game, round, answers, err := h.activeAnswers(gameID)
+	if err != nil {
+		log.Println("build all_answers:", err)
+		return
+	}
+
+	viewers := []string{""}
+	for conn := range room.Members {
+		if conn.PlayerID != nil {
+			viewers = append(viewers, *conn.PlayerID)
+		}
+	}
+
+	views := map[string]*Message{}
+
+	for _, viewerId := range viewers {
+		if _, ok := views[viewerId]; ok {
+			continue
+		}
+
+		views[viewerId], err = NewMessage("all_answers", AnswersFor(*game, *round, answers, viewerId))
+		if err != nil {
+			log.Println("build all_answers:", err)
+			return
+		}
+	}
+
+	room.BroadcastViews(views)
+}
+
+func (h *Hub) BroadcastRounds(gameID string) {
+	h.broadcastMessage(gameID, "get_rounds", h.RoundsMessage)
+}
+
+func (h *Hub) BroadcastText(gameID string) {
+	h.broadcastMessage(gameID, "set_text", h.TextMessage)
+}
+
+func (h *Hub) BroadcastConnectedPlayers(gameID string) {
+	h.broadcastMessage(gameID, "get_connected_players", h.ConnectedPlayersMessage)
+	h.BroadcastPresence(gameID)
+}
+
+// BroadcastGames sends the game listing to every connection, since it is
+// shown to players who have not joined a game yet.
+func (h *Hub) BroadcastGames() {
+	h.broadcastGames(h.connections)
+}
+
+// broadcastGames sends each connection its view of the game listing. Most
+// players only see public games, so they share one message.
+func (h *Hub) broadcastGames(conns map[*Connection]bool) {
+	games, err := h.store.FindAllGames()
+	if err != nil {
+		log.Println("find games:", err)
+		return
+	}
+
+	public, err := NewMessage("get_games", VisibleGames(games, ""))
+	if err != nil {
+		log.Println("build get_games:", err)
+		return
+	}
+
+	for conn := range conns {
+		if conn.PlayerID == nil {
+			continue
+		}
+
+		msg := public
+
+		if slices.ContainsFunc(games, func(g Game) bool {
+			return g.Private && g.VisibleTo(*conn.PlayerID)
+		}) {
+			msg, err = NewMessage("get_games", VisibleGames(games, *conn.PlayerID))
+			if err != nil {
+				log.Println("build get_games:", err)
+				continue
+			}
+		}
+
+		err := conn.Send(msg)
+		if err != nil {
+			log.Println("write:", err)
+		}
+	}
+}
+
+// SyncRooms makes every connection of playerId a member of the rooms of
+// exactly the games the player plays or moderates.
+func (h *Hub) SyncRooms(playerId string) {
+	games, err := h.playerGames(playerId)
+	if err != nil {
+		log.Println("find player games:", err)
+		return
+	}
+
+	for conn := range h.connections {
+		if conn.PlayerID == nil || *conn.PlayerID != playerId {
+			continue
+		}
+
+		for gameID, room := range conn.rooms {
+			member := room.Members[conn]
+			if member && !slices.ContainsFunc(games, func(g Game) bool { return g.ID == gameID }) {
+				h.LeaveRoom(conn, gameID)
+			}
+		}
+
+		for _, game := range games {
+			h.JoinRoom(conn, game)
+		}
+	}
+}
+
+// Subscribe adds the connection to the room of another game it is part of,
+// so it gets that game's events too, and sends it a snapshot of the game.
+func (c *Connection) Subscribe() (*Game, error) {
+	game, err := c.GetActiveGame()
+	if err != nil {
+		return nil, err
+	}
+
+	// Spectators stay spectators and are only brought up to date.
+	if room, ok := c.rooms[game.ID]; ok && room.Spectators[c] {
+		return game, c.sendSpectatorSnapshot(*game)
+	}
+
+	c.hub.JoinRoom(c, *game)
+
+	err = c.SendSnapshot(*game)
+	if err != nil {
+		return nil, err
+	}
+
+	return game, nil
+}
+
+func (c *Connection) Unsubscribe(msg SocketMessage) error {
+	var payload GameIDPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return InvalidPayload(err)
+	}
+
+	c.hub.LeaveRoom(c, payload.GameID)
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// BenchmarkBroadcast broadcasts to one game while the hub holds more and
+// more unrelated ones. The cost per broadcast should not grow with them.
+func BenchmarkBroadcast(b *testing.B) {
+	const membersPerRoom = 20
+
+	for _, rooms := range []int{1, 100, 10000} {
+		b.Run(fmt.Sprintf("rooms=%d", rooms), func(b *testing.B) {
+			config, err := LoadConfig()
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			hub := NewHub(config, NewMemoryStore(), nil)
+
+			for i := range rooms {
+				room := hub.Room(fmt.Sprintf("game-%d", i))
+
+				for range membersPerRoom {
+					conn := NewConnection(hub, nil, nil)
+					hub.connections[conn] = true
+					room.Join(conn, false)
+				}
+			}
+
+			target := hub.rooms["game-0"]
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for range b.N {
+				msg, err := NewMessage("set_text", "What is the capital of France?")
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				hub.Broadcast("game-0", msg)
+
+				for conn := range target.Members {
+					<-conn.send
+				}
+			}
+		})
+	}
+}
+
+func TestLeaveGameRemovesEveryTab(t *testing.T) {
+	hub := newTestHub(t)
+
+	mod := connect(t, hub, nil)
+	defer mod.close()
+	mod.mustHello("Mod")
+
+	var game Game
+	mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &game)
+
+	player := connect(t, hub, nil)
+	defer player.close()
+	playerID := player.mustHello("Player")
+	player.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+
+	tab := connect(t, hub, &Session{PlayerID: playerID})
+	defer tab.close()
+	tab.mustHello("Player")
+
+	members := func() int {
+		var n int
+		hub.Do(func() {
+			for conn := range hub.rooms[game.ID].Members {
+				if conn == player.conn || conn == tab.conn {
+					n++
+				}
+			}
+		})
+
+		return n
+	}
+
+	if n := members(); n != 2 {
+		t.Fatalf("%d tabs of the player are in the room, want 2", n)
+	}
+
+	player.mustRequest("leave_game", GameIDPayload{GameID: game.ID}, nil)
+
+	if n := members(); n != 0 {
+		t.Fatalf("%d tabs of the player are still in the room", n)
+	}
+
+	// The other tab no longer gets the game's events.
+	before := len(tab.messages("set_text"))
+	mod.mustRequest("set_text", map[string]any{"gameId": game.ID, "text": "Why?"}, nil)
+	tab.mustRequest("get_games", nil, nil)
+
+	if len(tab.messages("set_text")) != before {
+		t.Fatal("the other tab still got set_text")
+	}
+}
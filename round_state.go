@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RoundState is where a round is in its lifecycle:
+//
+//	draft → open → closed → revealed → archived
+//
+// A draft round is being prepared by the moderator, an open round takes
+// answers, a closed one no longer does, and a revealed round has its answers
+// shown to the players. Rounds are archived when the game moves on, which can
+// happen from any state.
+type RoundState string
+
+const (
+	RoundDraft    RoundState = "draft"
+	RoundOpen     RoundState = "open"
+	RoundClosed   RoundState = "closed"
+	RoundRevealed RoundState = "revealed"
+	RoundArchived RoundState = "archived"
+)
+
+// roundTransitions lists the states each state may move to.
+var roundTransitions = map[RoundState][]RoundState{
+	RoundDraft:    {RoundOpen, RoundArchived},
+	RoundOpen:     {RoundClosed, RoundArchived},
+	RoundClosed:   {RoundRevealed, RoundArchived},
+	RoundRevealed: {RoundArchived},
+	RoundArchived: {},
+}
+
+// CanTransition reports whether a round in state from may move to to.
+func CanTransition(from RoundState, to RoundState) bool {
+	return slices.Contains(roundTransitions[from], to)
+}
+
+// Transition moves the round to state to, or fails if the lifecycle does not
+// allow it.
+func (r *GameRound) Transition(to RoundState) error {
+	if !CanTransition(r.State, to) {
+		return NewCommandError(ErrCodeInvalidRoundState, "cannot move round from %s to %s", r.State, to)
+	}
+
+	r.State = to
+
+	return nil
+}
+
+// AcceptsAnswers fails unless the round is open and its deadline, if any,
+// has not passed.
+func (r GameRound) AcceptsAnswers(now time.Time) error {
+	if r.State == RoundDraft {
+		return ErrRoundNotStarted
+	}
+
+	if r.State != RoundOpen || r.Expired(now) {
+		return ErrRoundClosed
+	}
+
+	return nil
+}
+
+// Active reports whether the round is the current round of its game.
+func (r GameRound) Active() bool {
+	return r.State != RoundArchived
+}
+
+// Started reports whether the round takes answers.
+func (r GameRound) Started() bool {
+	return r.State == RoundOpen
+}
+
+// Ended reports whether the round stopped taking answers.
+func (r GameRound) Ended() bool {
+	return r.State == RoundClosed || r.State == RoundRevealed || r.State == RoundArchived
+}
+
+// RoundFields has the fields of GameRound without its methods, so it can be
+// embedded into roundDocument without recursing into the marshalers below.
+// It is exported only because bson skips unexported embedded structs.
+type RoundFields GameRound
+
+// roundDocument is how rounds are encoded. Next to the state it carries the
+// active/started/ended flags that the frontend and older documents use.
+type roundDocument struct {
+	RoundFields `bson:",inline"`
+	Active      bool `bson:"active" json:"active"`
+	Started     bool `bson:"started" json:"started"`
+	Ended       bool `bson:"ended" json:"ended"`
+}
+
+func newRoundDocument(r GameRound) roundDocument {
+	return roundDocument{
+		RoundFields: RoundFields(r),
+		Active:      r.Active(),
+		Started:     r.Started(),
+		Ended:       r.Ended(),
+	}
+}
+
+// round turns the document back into a round. Documents written before
+// rounds had a state get one derived from their flags.
+func (d roundDocument) round() GameRound {
+	r := GameRound(d.RoundFields)
+
+	if r.State == "" {
+		switch {
+		case !d.Active:
+			r.State = RoundArchived
+		case d.Started:
+			r.State = RoundOpen
+		case d.Ended:
+			r.State = RoundClosed
+		default:
+			r.State = RoundDraft
+		}
+	}
+
+	return r
+}
+
+func (r GameRound) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newRoundDocument(r))
+}
+
+func (r *GameRound) UnmarshalJSON(data []byte) error {
+	var d roundDocument
+	err := json.Unmarshal(data, &d)
+	if err != nil {
+		return err
+	}
+
+	*r = d.round()
+
+	return nil
+}
+
+func (r GameRound) MarshalBSON() ([]byte, error) {
+	return bson.Marshal(newRoundDocument(r))
+}
+
+func (r *GameRound) UnmarshalBSON(data []byte) error {
+	var d roundDocument
+	err := bson.Unmarshal(data, &d)
+	if err != nil {
+		return err
+	}
+
+	*r = d.round()
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var allRoundStates = []RoundState{RoundDraft, RoundOpen, RoundClosed, RoundRevealed, RoundArchived}
+
+func TestRoundTransitions(t *testing.T) {
+	allowed := map[[2]RoundState]bool{
+		{RoundDraft, RoundOpen}:        true,
+		{RoundDraft, RoundArchived}:    true,
+		{RoundOpen, RoundClosed}:       true,
+		{RoundOpen, RoundArchived}:     true,
+		{RoundClosed, RoundRevealed}:   true,
+		{RoundClosed, RoundArchived}:   true,
+		{RoundRevealed, RoundArchived}: true,
+	}
+
+	for _, from := range allRoundStates {
+		for _, to := range allRoundStates {
+			t.Run(string(from)+"->"+string(to), func(t *testing.T) {
+				want := allowed[[2]RoundState{from, to}]
+
+				if got := CanTransition(from, to); got != want {
+					t.Fatalf("CanTransition = %v, want %v", got, want)
+				}
+
+				round := GameRound{State: from}
+				err := round.Transition(to)
+
+				if want {
+					if err != nil || round.State != to {
+						t.Fatalf("Transition = %v, state %s", err, round.State)
+					}
+
+					return
+				}
+
+				var cmdErr *CommandError
+				if !errors.As(err, &cmdErr) || cmdErr.Code != ErrCodeInvalidRoundState {
+					t.Fatalf("Transition = %v, want %s", err, ErrCodeInvalidRoundState)
+				}
+
+				if round.State != from {
+					t.Fatalf("failed transition moved the round to %s", round.State)
+				}
+			})
+		}
+	}
+}
+
+// legacyFlags are the active/started/ended fields derived from each state.
+var legacyFlags = []struct {
+	state                  RoundState
+	active, started, ended bool
+}{
+	{RoundDraft, true, false, false},
+	{RoundOpen, true, true, false},
+	{RoundClosed, true, false, true},
+	{RoundRevealed, true, false, true},
+	{RoundArchived, false, false, true},
+}
+
+type roundFlags struct {
+	State   RoundState `json:"state" bson:"state"`
+	Active  bool       `json:"active" bson:"active"`
+	Started bool       `json:"started" bson:"started"`
+	Ended   bool       `json:"ended" bson:"ended"`
+}
+
+// roundCodecs encode rounds the ways they are stored and sent.
+var roundCodecs = []struct {
+	name      string
+	marshal   func(v any) ([]byte, error)
+	unmarshal func(data []byte, v any) error
+}{
+	{"json", json.Marshal, json.Unmarshal},
+	{"bson", bson.Marshal, bson.Unmarshal},
+}
+
+func TestRoundLegacyFieldsRoundTrip(t *testing.T) {
+	for _, codec := range roundCodecs {
+		for _, tt := range legacyFlags {
+			t.Run(codec.name+"/"+string(tt.state), func(t *testing.T) {
+				round := GameRound{ID: "r1", GameID: "g1", Round: 2, State: tt.state, Question: "Why?"}
+
+				data, err := codec.marshal(round)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var flags roundFlags
+				err = codec.unmarshal(data, &flags)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := roundFlags{tt.state, tt.active, tt.started, tt.ended}
+				if flags != want {
+					t.Fatalf("encoded %+v, want %+v", flags, want)
+				}
+
+				var decoded GameRound
+				err = codec.unmarshal(data, &decoded)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if decoded.State != tt.state || decoded.ID != "r1" || decoded.Round != 2 || decoded.Question != "Why?" {
+					t.Fatalf("decoded %+v", decoded)
+				}
+			})
+		}
+	}
+}
+
+// Documents written before rounds had a state only have the flags.
+func TestRoundStateFromLegacyFields(t *testing.T) {
+	tests := []struct {
+		flags roundFlags
+		want  RoundState
+	}{
+		{roundFlags{Active: true}, RoundDraft},
+		{roundFlags{Active: true, Started: true}, RoundOpen},
+		{roundFlags{Active: true, Ended: true}, RoundClosed},
+		{roundFlags{Active: false, Ended: true}, RoundArchived},
+		{roundFlags{Active: false}, RoundArchived},
+	}
+
+	for _, codec := range roundCodecs {
+		for _, tt := range tests {
+			data, err := codec.marshal(struct {
+				ID      string `json:"id" bson:"id"`
+				Active  bool   `json:"active" bson:"active"`
+				Started bool   `json:"started" bson:"started"`
+				Ended   bool   `json:"ended" bson:"ended"`
+			}{"r1", tt.flags.Active, tt.flags.Started, tt.flags.Ended})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var round GameRound
+			err = codec.unmarshal(data, &round)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if round.State != tt.want {
+				t.Errorf("%s %+v: state %s, want %s", codec.name, tt.flags, round.State, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"slices"
+)
+
+// Score is what the answer adds to its player's standing.
+func (a Answer) Score() int {
+	return a.Points + a.VotePoints
+}
+
+// Standing is one line of a game's leaderboard.
+type Standing struct {
+	PlayerID string `json:"playerId"`
+	Nickname string `json:"nickname"`
+	Points   int    `json:"points"`
+	// Rank is shared by players with the same points; the next rank skips
+	// the tied places (1, 1, 3).
+	Rank int `json:"rank"`
+	// Rounds holds the points per round number, for rounds the player
+	// answered in.
+	Rounds map[int]int `json:"rounds"`
+}
+
+// ComputeStandings adds up the points of every answer in the game. Players of
+// the game without points are listed with zero, players that left the game
+// keep the points they scored.
+func ComputeStandings(game Game, rounds []GameRound, answers []Answer, players []Player) []Standing {
+	roundNumbers := map[string]int{}
+	for _, r := range rounds {
+		roundNumbers[r.ID] = r.Round
+	}
+
+	nicknames := map[string]string{}
+	for _, p := range players {
+		nicknames[p.ID] = p.Nickname
+	}
+
+	byPlayer := map[string]*Standing{}
+	standing := func(playerId string) *Standing {
+		s, ok := byPlayer[playerId]
+		if !ok {
+			s = &Standing{
+				PlayerID: playerId,
+				Nickname: nicknames[playerId],
+				Rounds:   map[int]int{},
+			}
+			byPlayer[playerId] = s
+		}
+
+		return s
+	}
+
+	for _, id := range game.Players {
+		standing(id)
+	}
+
+	for _, a := range answers {
+		round, ok := roundNumbers[a.RoundID]
+		if !ok {
+			continue
+		}
+
+		s := standing(a.PlayerID)
+		s.Points += a.Score()
+		s.Rounds[round] += a.Score()
+	}
+
+	res := []Standing{}
+	for _, s := range byPlayer {
+		res = append(res, *s)
+	}
+
+	slices.SortFunc(res, func(a, b Standing) int {
+		return cmp.Or(
+			cmp.Compare(b.Points, a.Points),
+			cmp.Compare(a.Nickname, b.Nickname),
+			cmp.Compare(a.PlayerID, b.PlayerID),
+		)
+	})
+
+	for i := range res {
+		if i > 0 && res[i].Points == res[i-1].Points {
+			res[i].Rank = res[i-1].Rank
+		} else {
+			res[i].Rank = i + 1
+		}
+	}
+
+	return res
+}
+
+// Standings loads everything needed to compute the leaderboard of a game.
+func (h *Hub) Standings(gameID string) ([]Standing, error) {
+	game, err := h.store.FindGameById(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	rounds, err := h.store.FindRoundsByGameId(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := h.store.FindAnswersByGameId(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	playerIds := slices.Clone(game.Players)
+	for _, a := range answers {
+		playerIds = append(playerIds, a.PlayerID)
+	}
+
+	players, err := h.store.FindPlayersByIds(playerIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return ComputeStandings(*game, rounds, answers, players), nil
+}
+
+func (h *Hub) LeaderboardMessage(gameID string) (*Message, error) {
+	standings, err := h.Standings(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMessage("leaderboard", standings)
+}
+
+// BroadcastLeaderboard sends the player leaderboard, and the team
+// leaderboard for games played in teams.
+func (h *Hub) BroadcastLeaderboard(gameID string) {
+	h.broadcastMessage(gameID, "leaderboard", h.LeaderboardMessage)
+
+	game, err := h.store.FindGameById(gameID)
+	if err != nil {
+		log.Println("find game:", err)
+		return
+	}
+
+	if len(game.Teams) > 0 {
+		h.broadcastMessage(gameID, "team_leaderboard", h.TeamLeaderboardMessage)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestComputeStandings(t *testing.T) {
+	game := Game{ID: "g1", ModeratorUUID: "mod", Players: []string{"ann", "bob", "cat"}}
+	rounds := []GameRound{{ID: "r1", Round: 1}, {ID: "r2", Round: 2}}
+	players := []Player{{ID: "ann", Nickname: "Ann"}, {ID: "bob", Nickname: "Bob"}, {ID: "cat", Nickname: "Cat"}}
+
+	answer := func(playerId, roundId string, points int) Answer {
+		return Answer{ID: playerId + roundId, GameID: "g1", RoundID: roundId, PlayerID: playerId, Points: points}
+	}
+
+	tests := []struct {
+		name    string
+		answers []Answer
+		// want lists "player:points:rank" from the top.
+		want []string
+	}{
+		{
+			"nobody scored",
+			nil,
+			[]string{"ann:0:1", "bob:0:1", "cat:0:1"},
+		},
+		{
+			"shared ranks",
+			[]Answer{answer("ann", "r1", 2), answer("bob", "r1", 2), answer("cat", "r1", 1)},
+			[]string{"ann:2:1", "bob:2:1", "cat:1:3"},
+		},
+		{
+			"negative totals",
+			[]Answer{answer("ann", "r1", -3), answer("bob", "r1", 1), answer("bob", "r2", -2)},
+			[]string{"cat:0:1", "bob:-1:2", "ann:-3:3"},
+		},
+		{
+			"votes count",
+			[]Answer{answer("ann", "r1", 1), {ID: "v", RoundID: "r2", PlayerID: "cat", VotePoints: 2}},
+			[]string{"cat:2:1", "ann:1:2", "bob:0:3"},
+		},
+		{
+			"before rescoring round 1",
+			[]Answer{answer("ann", "r1", 1), answer("bob", "r1", 0), answer("bob", "r2", 1)},
+			[]string{"ann:1:1", "bob:1:1", "cat:0:3"},
+		},
+		{
+			"after rescoring round 1",
+			[]Answer{answer("ann", "r1", 0), answer("bob", "r1", 3), answer("bob", "r2", 1)},
+			[]string{"bob:4:1", "ann:0:2", "cat:0:2"},
+		},
+		{
+			"answers of unknown rounds do not count",
+			[]Answer{answer("ann", "gone", 5)},
+			[]string{"ann:0:1", "bob:0:1", "cat:0:1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, s := range ComputeStandings(game, rounds, tt.answers, players) {
+				got = append(got, fmt.Sprintf("%s:%d:%d", s.PlayerID, s.Points, s.Rank))
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("standings %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeStandingsKeepsPointsPerRound(t *testing.T) {
+	game := Game{ID: "g1", Players: []string{"ann"}}
+	rounds := []GameRound{{ID: "r1", Round: 1}, {ID: "r2", Round: 2}}
+	answers := []Answer{
+		{RoundID: "r1", PlayerID: "ann", Points: 2},
+		{RoundID: "r2", PlayerID: "ann", Points: -1, VotePoints: 3},
+		// Players that left keep their points.
+		{RoundID: "r2", PlayerID: "left", Points: 1},
+	}
+
+	standings := ComputeStandings(game, rounds, answers, nil)
+
+	if len(standings) != 2 {
+		t.Fatalf("got %d standings, want 2", len(standings))
+	}
+
+	ann := standings[0]
+	if ann.PlayerID != "ann" || ann.Points != 4 || ann.Rounds[1] != 2 || ann.Rounds[2] != 2 {
+		t.Fatalf("ann: %+v", ann)
+	}
+}
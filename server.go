@@ -1,23 +1,27 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 type Server struct {
 	*gin.Engine
 	Upgrader websocket.Upgrader
+	hub      *Hub
 }
 
-func NewServer() *Server {
+func NewServer(hub *Hub) *Server {
 	server := gin.New()
 
 	server.Use(gzip.Gzip(gzip.DefaultCompression))
@@ -48,6 +52,7 @@ func NewServer() *Server {
 				return false
 			},
 		},
+		hub,
 	}
 }
 
@@ -59,14 +64,281 @@ func (s *Server) GetGameById(c *gin.Context) {
 		return
 	}
 
-	for _, g := range games {
-		if g.ID == id {
-			c.JSON(http.StatusOK, g)
+	var game *Game
+	var err error
+
+	s.hub.Do(func() {
+		game, err = s.hub.store.FindGameById(id)
+	})
+
+	// Private games are only reachable through their join code.
+	if err != nil || game.Private {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, game)
+}
+
+func (s *Server) GetGameStandings(c *gin.Context) {
+	id := c.Param("id")
+
+	if id == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var game *Game
+	var standings []Standing
+	var err error
+
+	s.hub.Do(func() {
+		game, err = s.hub.store.FindGameById(id)
+		if err != nil || game.Private {
 			return
 		}
+
+		standings, err = s.hub.Standings(id)
+	})
+
+	if errors.Is(err, ErrGameNotFound) || (err == nil && game.Private) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
 	}
 
-	c.AbortWithStatus(http.StatusNotFound)
+	if err != nil {
+		log.Println("standings:", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, standings)
+}
+
+func (s *Server) GetGameTeamStandings(c *gin.Context) {
+	id := c.Param("id")
+
+	var game *Game
+	var standings []TeamStanding
+	var err error
+
+	s.hub.Do(func() {
+		game, err = s.hub.store.FindGameById(id)
+		if err != nil || game.Private {
+			return
+		}
+
+		standings, err = s.hub.TeamStandings(id)
+	})
+
+	if errors.Is(err, ErrGameNotFound) || (err == nil && game.Private) {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Println("team standings:", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, standings)
+}
+
+func (s *Server) GetGameByCode(c *gin.Context) {
+	code := NormalizeJoinCode(c.Param("code"))
+
+	if code == "" {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var game *Game
+	var err error
+
+	s.hub.Do(func() {
+		game, err = s.hub.store.FindGameByCode(code)
+	})
+
+	if err != nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, game)
+}
+
+// abortWithError responds with the same error payload websocket clients get.
+func abortWithError(c *gin.Context, command string, err error) {
+	payload := NewErrorPayload(command, err)
+
+	if payload.Code == ErrCodeInternal {
+		log.Printf("%s: %s", command, err)
+	}
+
+	c.AbortWithStatusJSON(HTTPStatus(payload.Code), payload)
+}
+
+// requireSession verifies the session token of a REST request.
+func (s *Server) requireSession(c *gin.Context, command string) (*Session, bool) {
+	token := sessionToken(c)
+	if token == "" {
+		abortWithError(c, command, NewCommandError(ErrCodeNotIdentified, "missing session token"))
+		return nil, false
+	}
+
+	session, err := s.hub.sessions.Verify(token)
+	if err != nil {
+		abortWithError(c, command, err)
+		return nil, false
+	}
+
+	return session, true
+}
+
+// findOwnQuestionSet loads a question set of the session's player. Sets of
+// other players are reported as not found.
+func (s *Server) findOwnQuestionSet(session *Session, id string) (*QuestionSet, error) {
+	var set *QuestionSet
+	var err error
+
+	s.hub.Do(func() {
+		set, err = s.hub.store.FindQuestionSetById(id)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if set.OwnerID != session.PlayerID {
+		return nil, ErrQuestionSetNotFound
+	}
+
+	return set, nil
+}
+
+func (s *Server) GetQuestionSets(c *gin.Context) {
+	session, ok := s.requireSession(c, "get_question_sets")
+	if !ok {
+		return
+	}
+
+	var sets []QuestionSet
+	var err error
+
+	s.hub.Do(func() {
+		sets, err = s.hub.store.FindQuestionSetsByOwnerId(session.PlayerID)
+	})
+
+	if err != nil {
+		abortWithError(c, "get_question_sets", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sets)
+}
+
+// ImportQuestionSet stores a question set sent as JSON, or as CSV with the
+// set's name in the name query parameter.
+func (s *Server) ImportQuestionSet(c *gin.Context) {
+	session, ok := s.requireSession(c, "import_question_set")
+	if !ok {
+		return
+	}
+
+	var payload QuestionSetPayload
+	var err error
+
+	if c.ContentType() == "text/csv" {
+		payload.Name = c.Query("name")
+		payload.Questions, err = ParseQuestionsCSV(c.Request.Body)
+	} else if err = c.ShouldBindJSON(&payload); err != nil {
+		err = InvalidPayload(err)
+	}
+
+	if err == nil {
+		err = payload.Validate()
+	}
+
+	if err != nil {
+		abortWithError(c, "import_question_set", err)
+		return
+	}
+
+	set := QuestionSet{
+		ID:        uuid.New().String(),
+		Name:      strings.TrimSpace(payload.Name),
+		OwnerID:   session.PlayerID,
+		Questions: payload.Questions,
+	}
+
+	s.hub.Do(func() {
+		err = s.hub.store.CreateQuestionSet(set)
+	})
+
+	if err != nil {
+		abortWithError(c, "import_question_set", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, set)
+}
+
+// ExportQuestionSet returns a question set as JSON, or as CSV when asked for
+// with format=csv or an Accept header of text/csv.
+func (s *Server) ExportQuestionSet(c *gin.Context) {
+	session, ok := s.requireSession(c, "export_question_set")
+	if !ok {
+		return
+	}
+
+	set, err := s.findOwnQuestionSet(session, c.Param("id"))
+	if err != nil {
+		abortWithError(c, "export_question_set", err)
+		return
+	}
+
+	if c.Query("format") == "csv" || c.NegotiateFormat(gin.MIMEJSON, "text/csv") == "text/csv" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", set.Name+".csv"))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+
+		err = WriteQuestionsCSV(c.Writer, set.Questions)
+		if err != nil {
+			log.Println("write csv:", err)
+		}
+
+		return
+	}
+
+	c.JSON(http.StatusOK, QuestionSetPayload{
+		Name:      set.Name,
+		Questions: set.Questions,
+	})
+}
+
+func (s *Server) DeleteQuestionSet(c *gin.Context) {
+	session, ok := s.requireSession(c, "delete_question_set")
+	if !ok {
+		return
+	}
+
+	set, err := s.findOwnQuestionSet(session, c.Param("id"))
+	if err != nil {
+		abortWithError(c, "delete_question_set", err)
+		return
+	}
+
+	s.hub.Do(func() {
+		err = s.hub.store.DeleteQuestionSet(set.ID)
+	})
+
+	if err != nil {
+		abortWithError(c, "delete_question_set", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 func (s *Server) RunWithLogs() {
@@ -76,16 +348,41 @@ func (s *Server) RunWithLogs() {
 }
 
 func (s *Server) HandleWebsocket(c *gin.Context) {
-	cookie, err := c.Request.Cookie("uuid")
-	var playerId *string
+	var session *Session
 
-	if err != nil {
-		fmt.Printf("Failed to get cookie: %s\n", err)
-	} else {
-		playerId = &cookie.Value
+	if token := sessionToken(c); token != "" {
+		verified, err := s.hub.sessions.Verify(token)
+
+		if err != nil {
+			fmt.Printf("Failed to verify session: %s\n", err)
+		} else {
+			session = verified
+		}
+	}
+
+	// The frontend neither reads set_session nor sends a token, so browsers
+	// keep their identity in an HttpOnly cookie instead. Clients without a
+	// valid one get it here, for a player that is created on their hello.
+	header := http.Header{}
+
+	if session == nil || s.hub.sessions.NeedsRotation(*session) {
+		playerId := uuid.New().String()
+		if session != nil {
+			playerId = session.PlayerID
+		}
+
+		token, issued, err := s.hub.sessions.Issue(playerId)
+		if err != nil {
+			log.Println("issue session:", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		session = &issued
+		header.Add("Set-Cookie", sessionCookie(c, token, issued).String())
 	}
 
-	conn, err := s.Upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := s.Upgrader.Upgrade(c.Writer, c.Request, header)
 	if err != nil {
 		err := c.AbortWithError(http.StatusInternalServerError, err)
 
@@ -99,16 +396,49 @@ func (s *Server) HandleWebsocket(c *gin.Context) {
 	defer conn.Close()
 	log.Println("connected")
 
-	con := Connection{
-		Conn:     conn,
-		PlayerID: playerId,
-	}
+	con := NewConnection(s.hub, conn, session)
+
+	go con.writePump()
 
-	connections = append(connections, &con)
+	s.hub.Register(con)
 
 	con.Listen()
 }
 
+// sessionToken returns the session token a client connects with, taken from
+// a bearer Authorization header, the token query parameter or the session
+// cookie.
+func sessionToken(c *gin.Context) string {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	if token := c.Query("token"); token != "" {
+		return token
+	}
+
+	cookie, err := c.Request.Cookie("session")
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// sessionCookie carries a session token for browsers. Scripts cannot read
+// it, and it is only sent over TLS if the request came in that way.
+func sessionCookie(c *gin.Context, token string, session Session) *http.Cookie {
+	return &http.Cookie{
+		Name:     "session",
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
 type HelloBody struct {
 	Nickname string `json:"nickname"`
 	UUID     string `json:"uuid"`
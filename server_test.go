@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer serves the websocket endpoint of a test hub.
+func newTestServer(t *testing.T, hub *Hub) *httptest.Server {
+	server := NewServer(hub)
+	server.GET("/ws", server.HandleWebsocket)
+	server.RegisterAPI(server.Group("/api/v1"))
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// dial opens a websocket to ts the way the frontend does, with the given
+// cookies.
+func dial(t *testing.T, ts *httptest.Server, cookies ...*http.Cookie) (*websocket.Conn, *http.Response) {
+	header := http.Header{"Origin": {"http://localhost:5173"}}
+
+	for _, cookie := range cookies {
+		header.Add("Cookie", cookie.String())
+	}
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, resp
+}
+
+// call sends a v1 command over a real websocket and returns its ack result.
+func call(t *testing.T, conn *websocket.Conn, kind string, payload any) json.RawMessage {
+	t.Helper()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = conn.WriteJSON(SocketMessage{Version: ProtocolVersion, Type: kind, RequestID: kind, Payload: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for {
+		var msg SocketMessage
+		err := conn.ReadJSON(&msg)
+		if err != nil {
+			t.Fatalf("%s: %s", kind, err)
+		}
+
+		if msg.RequestID != kind {
+			continue
+		}
+
+		if msg.Type == "error" {
+			t.Fatalf("%s: %s", kind, msg.Payload)
+		}
+
+		var ack struct {
+			Result json.RawMessage `json:"result"`
+		}
+
+		err = json.Unmarshal(msg.Payload, &ack)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return ack.Result
+	}
+}
+
+// TestSessionCookieKeepsIdentity reloads the page of a moderator whose
+// client only keeps cookies, and checks they still run their game.
+func TestSessionCookieKeepsIdentity(t *testing.T) {
+	ts := newTestServer(t, newTestHub(t))
+
+	conn, resp := dial(t, ts)
+
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "session" {
+			cookie = c
+		}
+	}
+
+	if cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("upgrade set no HttpOnly session cookie: %v", resp.Header["Set-Cookie"])
+	}
+
+	var player Player
+	json.Unmarshal(call(t, conn, "say_hello", SayHelloPayload{Name: "Mod"}), &player)
+
+	var game Game
+	json.Unmarshal(call(t, conn, "create_game", CreateGamePayload{Name: "Quiz"}), &game)
+
+	conn.Close()
+
+	reloaded, resp := dial(t, ts, cookie)
+
+	for _, c := range resp.Cookies() {
+		if c.Name == "session" {
+			t.Fatal("a valid session cookie was replaced")
+		}
+	}
+
+	var again Player
+	json.Unmarshal(call(t, reloaded, "say_hello", SayHelloPayload{Name: "Mod"}), &again)
+
+	if again.ID != player.ID {
+		t.Fatalf("reload made a new player %s, want %s", again.ID, player.ID)
+	}
+
+	call(t, reloaded, "set_text", map[string]string{"gameId": game.ID, "text": "Still the moderator?"})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSessionInvalid = NewCommandError(ErrCodeSessionInvalid, "invalid session token")
+	ErrSessionExpired = NewCommandError(ErrCodeSessionExpired, "session token expired")
+)
+
+// Session is the verified content of a session token. The player ID is
+// public and shared with every client, the token that proves it is not.
+type Session struct {
+	PlayerID  string    `json:"pid"`
+	IssuedAt  time.Time `json:"iat"`
+	ExpiresAt time.Time `json:"exp"`
+
+	// signedWithOldSecret is set when the token verified against one of the
+	// previous secrets and should be replaced.
+	signedWithOldSecret bool
+}
+
+type SessionPayload struct {
+	Token     string    `json:"token"`
+	PlayerID  string    `json:"playerId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// SessionManager issues HMAC signed session tokens and verifies them. New
+// tokens are signed with the first secret; the others are previous secrets
+// that are still accepted so secrets can be rotated without logging
+// everybody out.
+type SessionManager struct {
+	secrets [][]byte
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+func NewSessionManager(secrets []string, ttl time.Duration) (*SessionManager, error) {
+	if len(secrets) == 0 || secrets[0] == "" {
+		return nil, errors.New("no session secret configured")
+	}
+
+	m := &SessionManager{
+		ttl: ttl,
+		now: time.Now,
+	}
+
+	for _, s := range secrets {
+		if s != "" {
+			m.secrets = append(m.secrets, []byte(s))
+		}
+	}
+
+	return m, nil
+}
+
+// RandomSecret returns a secret for servers without a configured one.
+// Sessions signed with it do not survive a restart.
+func RandomSecret() string {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func (m *SessionManager) sign(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Issue creates a new token for playerId.
+func (m *SessionManager) Issue(playerId string) (string, Session, error) {
+	now := m.now()
+
+	session := Session{
+		PlayerID:  playerId,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(m.ttl),
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", Session{}, err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + m.sign(m.secrets[0], payload), session, nil
+}
+
+// Verify checks the signature and expiry of token.
+func (m *SessionManager) Verify(token string) (*Session, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrSessionInvalid
+	}
+
+	valid := -1
+	for i, secret := range m.secrets {
+		if hmac.Equal([]byte(signature), []byte(m.sign(secret, payload))) {
+			valid = i
+			break
+		}
+	}
+
+	if valid < 0 {
+		return nil, ErrSessionInvalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrSessionInvalid
+	}
+
+	var session Session
+	err = json.Unmarshal(data, &session)
+	if err != nil || session.PlayerID == "" {
+		return nil, ErrSessionInvalid
+	}
+
+	if !m.now().Before(session.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+
+	session.signedWithOldSecret = valid > 0
+
+	return &session, nil
+}
+
+// NeedsRotation reports whether a verified session should be replaced by a
+// fresh token: once half of its lifetime has passed, or when it was signed
+// with a previous secret.
+func (m *SessionManager) NeedsRotation(session Session) bool {
+	if session.signedWithOldSecret {
+		return true
+	}
+
+	return m.now().After(session.IssuedAt.Add(m.ttl / 2))
+}
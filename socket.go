@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Socket is the part of *websocket.Conn a Connection uses, so connections
+// can run on something other than a real socket.
+type Socket interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	Close() error
+}
+
+// extendReadDeadline gives the client another PongWait to show it is still
+// there. Every pong and every message it sends counts.
+func (c *Connection) extendReadDeadline() {
+	err := c.Conn.SetReadDeadline(time.Now().Add(c.hub.config.PongWait))
+	if err != nil {
+		log.Println("set read deadline:", err)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeSocket stands in for the websocket of a client. The test plays the
+// client: it queues messages for the server to read with deliver and looks
+// at what the server wrote with frames.
+type fakeSocket struct {
+	in     chan []byte
+	closed chan struct{}
+	once   sync.Once
+	// written is signalled whenever a frame was written.
+	written chan struct{}
+
+	mu            sync.Mutex
+	out           []fakeFrame
+	text          [][]byte
+	readDeadline  time.Time
+	writeDeadline time.Time
+	pongHandler   func(appData string) error
+	// autoPong answers pings the way browsers do.
+	autoPong bool
+	// stall makes writes hang until their deadline passes, like a client
+	// that stopped reading.
+	stall bool
+}
+
+type fakeFrame struct {
+	messageType int
+	data        []byte
+}
+
+func newFakeSocket() *fakeSocket {
+	return &fakeSocket{
+		in:       make(chan []byte, 16),
+		closed:   make(chan struct{}),
+		written:  make(chan struct{}, 1),
+		autoPong: true,
+	}
+}
+
+// deliver sends data to the server as if the client wrote it.
+func (s *fakeSocket) deliver(data []byte) {
+	select {
+	case s.in <- data:
+	case <-s.closed:
+	}
+}
+
+// frames returns the text frames written after the first skip ones.
+func (s *fakeSocket) frames(skip int) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.text[min(skip, len(s.text)):]
+}
+
+func (s *fakeSocket) count(messageType int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, f := range s.out {
+		if f.messageType == messageType {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *fakeSocket) isClosed() bool {
+	select {
+	case <-s.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *fakeSocket) ReadMessage() (int, []byte, error) {
+	for {
+		s.mu.Lock()
+		deadline := s.readDeadline
+		s.mu.Unlock()
+
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			timeout = time.After(time.Until(deadline))
+		}
+
+		select {
+		case data := <-s.in:
+			return websocket.TextMessage, data, nil
+		case <-s.closed:
+			return 0, nil, errors.New("socket closed")
+		case <-timeout:
+			// A pong may have moved the deadline in the meantime.
+			s.mu.Lock()
+			extended := s.readDeadline.After(deadline)
+			s.mu.Unlock()
+
+			if !extended {
+				return 0, nil, os.ErrDeadlineExceeded
+			}
+		}
+	}
+}
+
+func (s *fakeSocket) WriteMessage(messageType int, data []byte) error {
+	if s.isClosed() {
+		return errors.New("socket closed")
+	}
+
+	s.mu.Lock()
+	stall, deadline := s.stall, s.writeDeadline
+	s.mu.Unlock()
+
+	if stall {
+		select {
+		case <-time.After(time.Until(deadline)):
+			return os.ErrDeadlineExceeded
+		case <-s.closed:
+			return errors.New("socket closed")
+		}
+	}
+
+	s.mu.Lock()
+	s.out = append(s.out, fakeFrame{messageType, data})
+	if messageType == websocket.TextMessage {
+		s.text = append(s.text, data)
+	}
+	pong := s.pongHandler
+	autoPong := s.autoPong
+	s.mu.Unlock()
+
+	select {
+	case s.written <- struct{}{}:
+	default:
+	}
+
+	if messageType == websocket.PingMessage && autoPong && pong != nil {
+		return pong(string(data))
+	}
+
+	return nil
+}
+
+func (s *fakeSocket) SetReadDeadline(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.readDeadline = t
+	return nil
+}
+
+func (s *fakeSocket) SetWriteDeadline(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writeDeadline = t
+	return nil
+}
+
+func (s *fakeSocket) SetPongHandler(h func(appData string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pongHandler = h
+}
+
+func (s *fakeSocket) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+
+	return nil
+}
+
+// useTimeouts shortens the heartbeat and write timeouts of the hub for the
+// connections made afterwards.
+func useTimeouts(hub *Hub, pingInterval, pongWait, writeWait time.Duration) {
+	hub.Do(func() {
+		hub.config.PingInterval = pingInterval
+		hub.config.PongWait = pongWait
+		hub.config.WriteWait = writeWait
+	})
+}
+
+func TestMissedPongsUnregister(t *testing.T) {
+	hub := newTestHub(t)
+	useTimeouts(hub, 20*time.Millisecond, 100*time.Millisecond, time.Second)
+
+	alive := connect(t, hub, nil)
+	defer alive.close()
+
+	silent := connect(t, hub, nil)
+	silent.socket.mu.Lock()
+	silent.socket.autoPong = false
+	silent.socket.mu.Unlock()
+
+	waitForConnections(t, hub, 1)
+
+	if !silent.socket.isClosed() {
+		t.Fatal("the socket of a client that missed its pongs is still open")
+	}
+
+	if silent.socket.count(websocket.PingMessage) == 0 {
+		t.Fatal("the client was never pinged")
+	}
+
+	var registered bool
+	hub.Do(func() {
+		registered = hub.connections[alive.conn]
+	})
+
+	if !registered || alive.socket.isClosed() {
+		t.Fatal("a client answering pings was dropped")
+	}
+}
+
+func TestWriteTimeoutClosesConnection(t *testing.T) {
+	hub := newTestHub(t)
+	useTimeouts(hub, time.Minute, 2*time.Minute, 50*time.Millisecond)
+
+	c := connect(t, hub, nil)
+	c.socket.mu.Lock()
+	c.socket.stall = true
+	c.socket.mu.Unlock()
+
+	waitForConnections(t, hub, 1)
+	c.send("say_hello", SayHelloPayload{Name: "Stuck"})
+
+	select {
+	case <-c.socket.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the socket is still open after a write timed out")
+	}
+
+	waitForConnections(t, hub, 0)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+// spectatorMessages are the message types spectators receive: what a big
+// screen shows, and nothing that gives unrevealed answers away.
+var spectatorMessages = map[string]bool{
+	"set_text":         true,
+	"get_rounds":       true,
+	"round_timer":      true,
+	"leaderboard":      true,
+	"team_leaderboard": true,
+	"choice_results":   true,
+	"ballot":           true,
+	"vote_results":     true,
+	"buzzer":           true,
+	"presence":         true,
+	"game_deleted":     true,
+}
+
+// JoinRoomAsSpectator adds c to the game's room without making it a member.
+func (h *Hub) JoinRoomAsSpectator(c *Connection, game Game) {
+	room := h.Room(game.ID)
+
+	room.Spectators[c] = true
+	c.rooms[game.ID] = room
+
+	h.BroadcastPresence(game.ID)
+}
+
+// Spectate lets a display follow a game by its join code without joining
+// it. Spectators need no identity, but have to know the password of
+// protected games.
+func (c *Connection) Spectate(msg SocketMessage) (*Game, error) {
+	var payload JoinGamePayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	var game *Game
+
+	if payload.Code != "" {
+		game, err = c.hub.store.FindGameByCode(NormalizeJoinCode(payload.Code))
+	} else {
+		game, err = c.hub.store.FindGameById(payload.GameID)
+
+		if err == nil && game.Private {
+			game, err = nil, ErrGameNotFound
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = game.CheckPassword(payload.Password, msg.passwordHash)
+	if err != nil {
+		return nil, err
+	}
+
+	c.hub.JoinRoomAsSpectator(c, *game)
+
+	err = c.sendSpectatorSnapshot(*game)
+	if err != nil {
+		return nil, err
+	}
+
+	return game, nil
+}
+
+// sendSpectatorSnapshot brings a new spectator up to date.
+func (c *Connection) sendSpectatorSnapshot(game Game) error {
+	err := c.sendPayload("spectate", game)
+	if err != nil {
+		return err
+	}
+
+	builders := []func(string) (*Message, error){
+		c.hub.RoundsMessage,
+		c.hub.TextMessage,
+		func(gameID string) (*Message, error) {
+			return c.hub.AnswersMessage(gameID, "")
+		},
+		c.hub.LeaderboardMessage,
+	}
+
+	if len(game.Teams) > 0 {
+		builders = append(builders, c.hub.TeamLeaderboardMessage)
+	}
+
+	for _, build := range builders {
+		msg, err := build(game.ID)
+		if errors.Is(err, ErrRoundNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = c.Send(msg)
+		if err != nil {
+			log.Println("write:", err)
+		}
+	}
+
+	if t, ok := c.hub.timers[game.ID]; ok {
+		msg, err := c.hub.RoundTimerMessage(t)
+		if err != nil {
+			return err
+		}
+
+		err = c.Send(msg)
+		if err != nil {
+			log.Println("write:", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Connection) StopSpectating(msg SocketMessage) error {
+	var payload GameIDPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return InvalidPayload(err)
+	}
+
+	room, ok := c.rooms[payload.GameID]
+	if !ok || !room.Spectators[c] {
+		return ErrGameNotFound
+	}
+
+	c.hub.LeaveRoom(c, payload.GameID)
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestSpectatorGetsRoundTimerAlone(t *testing.T) {
+	r := startTimedRound(t, 30)
+
+	var game *Game
+	var err error
+	r.hub.Do(func() {
+		game, err = r.hub.store.FindGameById(r.gameID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spectator := connect(t, r.hub, nil)
+	defer spectator.close()
+	spectator.mustRequest("spectate", JoinGamePayload{Code: game.Code}, nil)
+
+	spectator.waitFor(func(m SocketMessage) bool { return m.Type == "round_timer" })
+
+	// Replies come after everything queued before them.
+	r.player.mustRequest("get_text", GameIDPayload{GameID: r.gameID}, nil)
+
+	if n := len(r.player.messages("round_timer")); n != 1 {
+		t.Fatalf("player got %d round timers, want only the one from the start", n)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import "fmt"
+
+// The not found errors are command errors, so handlers can hand them to the
+// client unchanged.
+var (
+	ErrGameNotFound   = NewCommandError(ErrCodeGameNotFound, "game not found")
+	ErrRoundNotFound  = NewCommandError(ErrCodeRoundNotFound, "round not found")
+	ErrPlayerNotFound = NewCommandError(ErrCodePlayerNotFound, "player not found")
+	ErrAnswerNotFound = NewCommandError(ErrCodeAnswerNotFound, "answer not found")
+
+	ErrQuestionSetNotFound = NewCommandError(ErrCodeQuestionSetNotFound, "question set not found")
+)
+
+// GameStore persists games, rounds, players, answers and question sets. Implementations
+// hand out copies, so every change has to be written back through one of the
+// Create/Update/Delete methods.
+type GameStore interface {
+	FindAllGames() ([]Game, error)
+	FindGameById(id string) (*Game, error)
+	FindGameByCode(code string) (*Game, error)
+	FindGamesByPlayerId(playerId string) ([]Game, error)
+	FindGamesByModeratorId(moderatorId string) ([]Game, error)
+	CreateGame(game Game) error
+	UpdateGame(game Game) error
+	UpdateGamePlayers(id string, players []string) error
+	DeleteGame(id string) error
+
+	FindPlayerById(id string) (*Player, error)
+	FindPlayersByIds(ids []string) ([]Player, error)
+	CreatePlayer(player Player) error
+	UpdatePlayer(player Player) error
+	DeletePlayer(id string) error
+
+	FindRoundById(id string) (*GameRound, error)
+	FindRoundsByGameId(gameId string) ([]GameRound, error)
+	FindActiveRoundByGameId(gameId string) (*GameRound, error)
+	CreateRound(round GameRound) error
+	UpdateRound(round GameRound) error
+	DeleteRound(id string) error
+	DeleteRoundsByGameId(gameId string) error
+
+	FindAnswerById(id string) (*Answer, error)
+	FindAnswerByPlayer(gameId string, roundId string, playerId string) (*Answer, error)
+	FindAllAnswersByGameAndRound(gameId string, roundId string) ([]Answer, error)
+	FindAnswersByGameId(gameId string) ([]Answer, error)
+	CreateAnswer(answer Answer) error
+	UpdateAnswer(answer Answer) error
+	DeleteAnswer(id string) error
+	DeleteAnswersByGameId(gameId string) error
+
+	FindQuestionSetById(id string) (*QuestionSet, error)
+	FindQuestionSetsByOwnerId(ownerId string) ([]QuestionSet, error)
+	CreateQuestionSet(set QuestionSet) error
+	UpdateQuestionSet(set QuestionSet) error
+	DeleteQuestionSet(id string) error
+}
+
+func NewStore(config Config) (GameStore, error) {
+	switch config.Store {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "mongo":
+		return NewMongoStore(config.MongoURI, config.MongoDatabase)
+	}
+
+	return nil, fmt.Errorf("unknown store %q", config.Store)
+}
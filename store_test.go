@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testGameStore(t, func(t *testing.T) GameStore {
+		return NewMemoryStore()
+	})
+}
+
+// TestMongoStore runs against the mongod in MONGO_URI, each test in a
+// database of its own that is dropped afterwards.
+func TestMongoStore(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+
+	testGameStore(t, func(t *testing.T) GameStore {
+		s, err := NewMongoStore(uri, fmt.Sprintf("league-game-test-%d", time.Now().UnixNano()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+			defer cancel()
+
+			s.games.Database().Drop(ctx)
+			s.client.Disconnect(ctx)
+		})
+
+		return s
+	})
+}
+
+// testGameStore checks the behaviour every GameStore must share. newStore
+// returns an empty store.
+func testGameStore(t *testing.T, newStore func(t *testing.T) GameStore) {
+	t.Run("games", func(t *testing.T) {
+		s := newStore(t)
+
+		game := Game{ID: "g1", Code: "ABC234", Name: "Quiz", ModeratorUUID: "mod", Players: []string{"p1"}}
+		other := Game{ID: "g2", Code: "XYZ789", Name: "Other", ModeratorUUID: "p1", Players: []string{}}
+
+		for _, g := range []Game{game, other} {
+			if err := s.CreateGame(g); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		found, err := s.FindGameById("g1")
+		if err != nil || found.Name != "Quiz" || !slices.Equal(found.Players, []string{"p1"}) {
+			t.Fatalf("FindGameById = %+v, %v", found, err)
+		}
+
+		found, err = s.FindGameByCode("ABC234")
+		if err != nil || found.ID != "g1" {
+			t.Fatalf("FindGameByCode = %+v, %v", found, err)
+		}
+
+		games, err := s.FindAllGames()
+		if err != nil || len(games) != 2 {
+			t.Fatalf("FindAllGames = %d games, %v", len(games), err)
+		}
+
+		games, err = s.FindGamesByPlayerId("p1")
+		if err != nil || len(games) != 1 || games[0].ID != "g1" {
+			t.Fatalf("FindGamesByPlayerId = %+v, %v", games, err)
+		}
+
+		games, err = s.FindGamesByModeratorId("p1")
+		if err != nil || len(games) != 1 || games[0].ID != "g2" {
+			t.Fatalf("FindGamesByModeratorId = %+v, %v", games, err)
+		}
+
+		// Stores hand out copies.
+		found.Players[0] = "changed"
+		found, _ = s.FindGameById("g1")
+		if found.Players[0] != "p1" {
+			t.Fatal("changing a found game changed the stored one")
+		}
+
+		if err := s.UpdateGamePlayers("g1", []string{"p1", "p2"}); err != nil {
+			t.Fatal(err)
+		}
+
+		games, _ = s.FindGamesByPlayerId("p2")
+		if len(games) != 1 {
+			t.Fatalf("FindGamesByPlayerId after UpdateGamePlayers = %+v", games)
+		}
+
+		game.Name = "Renamed"
+		game.Private = true
+		if err := s.UpdateGame(game); err != nil {
+			t.Fatal(err)
+		}
+
+		found, _ = s.FindGameById("g1")
+		if found.Name != "Renamed" || !found.Private {
+			t.Fatalf("UpdateGame did not stick: %+v", found)
+		}
+
+		if err := s.DeleteGame("g1"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.FindGameById("g1"); !errors.Is(err, ErrGameNotFound) {
+			t.Fatalf("FindGameById after delete = %v, want ErrGameNotFound", err)
+		}
+
+		if _, err := s.FindGameByCode("ABC234"); !errors.Is(err, ErrGameNotFound) {
+			t.Fatalf("FindGameByCode after delete = %v, want ErrGameNotFound", err)
+		}
+
+		if err := s.UpdateGame(game); !errors.Is(err, ErrGameNotFound) {
+			t.Fatalf("UpdateGame of a deleted game = %v, want ErrGameNotFound", err)
+		}
+
+		if err := s.DeleteGame("g1"); !errors.Is(err, ErrGameNotFound) {
+			t.Fatalf("DeleteGame twice = %v, want ErrGameNotFound", err)
+		}
+	})
+
+	t.Run("players", func(t *testing.T) {
+		s := newStore(t)
+
+		for _, p := range []Player{{ID: "p1", Nickname: "Ann"}, {ID: "p2", Nickname: "Bob"}} {
+			if err := s.CreatePlayer(p); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		players, err := s.FindPlayersByIds([]string{"p1", "p2", "unknown"})
+		if err != nil || len(players) != 2 {
+			t.Fatalf("FindPlayersByIds = %+v, %v", players, err)
+		}
+
+		if err := s.UpdatePlayer(Player{ID: "p1", Nickname: "Anna"}); err != nil {
+			t.Fatal(err)
+		}
+
+		player, err := s.FindPlayerById("p1")
+		if err != nil || player.Nickname != "Anna" {
+			t.Fatalf("FindPlayerById = %+v, %v", player, err)
+		}
+
+		if err := s.DeletePlayer("p1"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.FindPlayerById("p1"); !errors.Is(err, ErrPlayerNotFound) {
+			t.Fatalf("FindPlayerById after delete = %v, want ErrPlayerNotFound", err)
+		}
+	})
+
+	t.Run("rounds", func(t *testing.T) {
+		s := newStore(t)
+
+		deadline := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		rounds := []GameRound{
+			{ID: "r1", GameID: "g1", Round: 1, State: RoundArchived, Answers: []Answer{}},
+			{ID: "r2", GameID: "g1", Round: 2, State: RoundOpen, Answers: []Answer{}, Deadline: &deadline},
+			{ID: "r3", GameID: "g2", Round: 1, State: RoundDraft, Answers: []Answer{}},
+		}
+
+		for _, r := range rounds {
+			if err := s.CreateRound(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		active, err := s.FindActiveRoundByGameId("g1")
+		if err != nil || active.ID != "r2" || active.State != RoundOpen || active.Deadline == nil || !active.Deadline.Equal(deadline) {
+			t.Fatalf("FindActiveRoundByGameId = %+v, %v", active, err)
+		}
+
+		found, err := s.FindRoundsByGameId("g1")
+		if err != nil || len(found) != 2 {
+			t.Fatalf("FindRoundsByGameId = %+v, %v", found, err)
+		}
+
+		active.State = RoundArchived
+		if err := s.UpdateRound(*active); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.FindActiveRoundByGameId("g1"); !errors.Is(err, ErrRoundNotFound) {
+			t.Fatalf("FindActiveRoundByGameId without active round = %v, want ErrRoundNotFound", err)
+		}
+
+		if err := s.DeleteRound("r3"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.FindRoundById("r3"); !errors.Is(err, ErrRoundNotFound) {
+			t.Fatalf("FindRoundById after delete = %v, want ErrRoundNotFound", err)
+		}
+
+		if err := s.DeleteRoundsByGameId("g1"); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err = s.FindRoundsByGameId("g1")
+		if err != nil || len(found) != 0 {
+			t.Fatalf("FindRoundsByGameId after DeleteRoundsByGameId = %+v, %v", found, err)
+		}
+	})
+
+	t.Run("answers", func(t *testing.T) {
+		s := newStore(t)
+
+		answers := []Answer{
+			{ID: "a1", GameID: "g1", RoundID: "r1", PlayerID: "p1", Text: "one"},
+			{ID: "a2", GameID: "g1", RoundID: "r1", PlayerID: "p2", Text: "two"},
+			{ID: "a3", GameID: "g1", RoundID: "r2", PlayerID: "p1", Text: "three"},
+			{ID: "a4", GameID: "g2", RoundID: "r3", PlayerID: "p1", Text: "four"},
+		}
+
+		for _, a := range answers {
+			if err := s.CreateAnswer(a); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		answer, err := s.FindAnswerByPlayer("g1", "r1", "p2")
+		if err != nil || answer.ID != "a2" {
+			t.Fatalf("FindAnswerByPlayer = %+v, %v", answer, err)
+		}
+
+		if _, err := s.FindAnswerByPlayer("g1", "r2", "p2"); !errors.Is(err, ErrAnswerNotFound) {
+			t.Fatalf("FindAnswerByPlayer without answer = %v, want ErrAnswerNotFound", err)
+		}
+
+		found, err := s.FindAllAnswersByGameAndRound("g1", "r1")
+		if err != nil || len(found) != 2 {
+			t.Fatalf("FindAllAnswersByGameAndRound = %+v, %v", found, err)
+		}
+
+		found, err = s.FindAnswersByGameId("g1")
+		if err != nil || len(found) != 3 {
+			t.Fatalf("FindAnswersByGameId = %+v, %v", found, err)
+		}
+
+		answer.Points = 3
+		answer.RevealedToPlayers = true
+		if err := s.UpdateAnswer(*answer); err != nil {
+			t.Fatal(err)
+		}
+
+		answer, err = s.FindAnswerById("a2")
+		if err != nil || answer.Points != 3 || !answer.RevealedToPlayers {
+			t.Fatalf("FindAnswerById after update = %+v, %v", answer, err)
+		}
+
+		if err := s.DeleteAnswer("a2"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.FindAnswerById("a2"); !errors.Is(err, ErrAnswerNotFound) {
+			t.Fatalf("FindAnswerById after delete = %v, want ErrAnswerNotFound", err)
+		}
+
+		if err := s.DeleteAnswersByGameId("g1"); err != nil {
+			t.Fatal(err)
+		}
+
+		found, _ = s.FindAnswersByGameId("g1")
+		remaining, _ := s.FindAnswersByGameId("g2")
+		if len(found) != 0 || len(remaining) != 1 {
+			t.Fatalf("DeleteAnswersByGameId left %d answers of g1 and %d of g2", len(found), len(remaining))
+		}
+	})
+
+	t.Run("question sets", func(t *testing.T) {
+		s := newStore(t)
+
+		set := QuestionSet{ID: "s1", Name: "Geography", OwnerID: "p1", Questions: []Question{{Text: "Capital of France?", Answer: "Paris"}}}
+
+		if err := s.CreateQuestionSet(set); err != nil {
+			t.Fatal(err)
+		}
+
+		sets, err := s.FindQuestionSetsByOwnerId("p1")
+		if err != nil || len(sets) != 1 || sets[0].Questions[0].Answer != "Paris" {
+			t.Fatalf("FindQuestionSetsByOwnerId = %+v, %v", sets, err)
+		}
+
+		set.Questions = append(set.Questions, Question{Text: "Capital of Spain?"})
+		if err := s.UpdateQuestionSet(set); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := s.FindQuestionSetById("s1")
+		if err != nil || len(found.Questions) != 2 {
+			t.Fatalf("FindQuestionSetById = %+v, %v", found, err)
+		}
+
+		if err := s.DeleteQuestionSet("s1"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.FindQuestionSetById("s1"); !errors.Is(err, ErrQuestionSetNotFound) {
+			t.Fatalf("FindQuestionSetById after delete = %v, want ErrQuestionSetNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTeamNotFound = NewCommandError(ErrCodeTeamNotFound, "team not found")
+	ErrNotCaptain   = NewCommandError(ErrCodeNotCaptain, "only team captains answer this round")
+)
+
+// AnswerMode decides who answers a round when the game is played in teams.
+type AnswerMode string
+
+const (
+	// AnswerIndividual lets every player answer, which is also how rounds
+	// without a mode behave.
+	AnswerIndividual AnswerMode = "individual"
+	// AnswerCaptain lets only the captain answer for their team.
+	AnswerCaptain AnswerMode = "captain"
+)
+
+type Team struct {
+	ID        string   `bson:"id" json:"id"`
+	Name      string   `bson:"name" json:"name"`
+	Players   []string `bson:"players" json:"players"`
+	CaptainID string   `bson:"captainId" json:"captainId"`
+}
+
+// TeamOf returns the team playerId plays in, if any.
+func (g *Game) TeamOf(playerId string) *Team {
+	for i := range g.Teams {
+		if slices.Contains(g.Teams[i].Players, playerId) {
+			return &g.Teams[i]
+		}
+	}
+
+	return nil
+}
+
+func (g *Game) findTeam(teamId string) *Team {
+	for i := range g.Teams {
+		if g.Teams[i].ID == teamId {
+			return &g.Teams[i]
+		}
+	}
+
+	return nil
+}
+
+// RemoveFromTeams takes playerId out of their team, handing the captaincy
+// to the next player. It reports whether anything changed.
+func (g *Game) RemoveFromTeams(playerId string) bool {
+	team := g.TeamOf(playerId)
+	if team == nil {
+		return false
+	}
+
+	team.Players = slices.DeleteFunc(team.Players, func(p string) bool {
+		return p == playerId
+	})
+
+	if team.CaptainID == playerId {
+		team.CaptainID = ""
+
+		if len(team.Players) > 0 {
+			team.CaptainID = team.Players[0]
+		}
+	}
+
+	return true
+}
+
+// AssignToTeam moves playerId into the team, making them captain if the team
+// has none.
+func (g *Game) AssignToTeam(playerId string, teamId string) error {
+	team := g.findTeam(teamId)
+	if team == nil {
+		return ErrTeamNotFound
+	}
+
+	g.RemoveFromTeams(playerId)
+
+	team = g.findTeam(teamId)
+	team.Players = append(team.Players, playerId)
+
+	if team.CaptainID == "" {
+		team.CaptainID = playerId
+	}
+
+	return nil
+}
+
+// ShuffleTeams spreads all players of the game evenly over its teams, in
+// random order. The first player drawn into a team becomes its captain.
+func (g *Game) ShuffleTeams() {
+	if len(g.Teams) == 0 {
+		return
+	}
+
+	players := slices.Clone(g.Players)
+	rand.Shuffle(len(players), func(i, j int) {
+		players[i], players[j] = players[j], players[i]
+	})
+
+	for i := range g.Teams {
+		g.Teams[i].Players = []string{}
+		g.Teams[i].CaptainID = ""
+	}
+
+	for i, p := range players {
+		team := &g.Teams[i%len(g.Teams)]
+		team.Players = append(team.Players, p)
+
+		if team.CaptainID == "" {
+			team.CaptainID = p
+		}
+	}
+}
+
+// CheckAnswerMode fails if playerId may not answer round for their team.
+func (r GameRound) CheckAnswerMode(game Game, playerId string) error {
+	if r.AnswerMode != AnswerCaptain {
+		return nil
+	}
+
+	team := game.TeamOf(playerId)
+	if team == nil || team.CaptainID != playerId {
+		return ErrNotCaptain
+	}
+
+	return nil
+}
+
+// answerTeam checks that playerId may answer round and returns the team
+// they answer for.
+func (h *Hub) answerTeam(round GameRound, playerId string) (string, error) {
+	game, err := h.store.FindGameById(round.GameID)
+	if err != nil {
+		return "", err
+	}
+
+	err = round.CheckAnswerMode(*game, playerId)
+	if err != nil {
+		return "", err
+	}
+
+	return game.TeamIDOf(playerId), nil
+}
+
+// TeamIDOf returns the ID of playerId's team, or "" without one.
+func (g *Game) TeamIDOf(playerId string) string {
+	if team := g.TeamOf(playerId); team != nil {
+		return team.ID
+	}
+
+	return ""
+}
+
+type CreateTeamPayload struct {
+	GameID string `json:"gameId"`
+	Name   string `json:"name"`
+}
+
+type TeamPayload struct {
+	GameID   string `json:"gameId"`
+	TeamID   string `json:"teamId"`
+	PlayerID string `json:"playerId"`
+}
+
+type AnswerModePayload struct {
+	Mode AnswerMode `json:"mode"`
+}
+
+func (p *AnswerModePayload) setLegacy(s string) {
+	p.Mode = AnswerMode(s)
+}
+
+// TeamStanding is one line of a game's team leaderboard.
+type TeamStanding struct {
+	TeamID  string   `json:"teamId"`
+	Name    string   `json:"name"`
+	Players []string `json:"players"`
+	Points  int      `json:"points"`
+	Rank    int      `json:"rank"`
+}
+
+// ComputeTeamStandings adds up the answers by the team they were given for.
+func ComputeTeamStandings(game Game, answers []Answer) []TeamStanding {
+	points := map[string]int{}
+	for _, a := range answers {
+		if a.TeamID != "" {
+			points[a.TeamID] += a.Score()
+		}
+	}
+
+	res := []TeamStanding{}
+	for _, t := range game.Teams {
+		res = append(res, TeamStanding{
+			TeamID:  t.ID,
+			Name:    t.Name,
+			Players: t.Players,
+			Points:  points[t.ID],
+		})
+	}
+
+	slices.SortFunc(res, func(a, b TeamStanding) int {
+		return cmp.Or(
+			cmp.Compare(b.Points, a.Points),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	for i := range res {
+		if i > 0 && res[i].Points == res[i-1].Points {
+			res[i].Rank = res[i-1].Rank
+		} else {
+			res[i].Rank = i + 1
+		}
+	}
+
+	return res
+}
+
+func (h *Hub) TeamStandings(gameID string) ([]TeamStanding, error) {
+	game, err := h.store.FindGameById(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, err := h.store.FindAnswersByGameId(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ComputeTeamStandings(*game, answers), nil
+}
+
+func (h *Hub) TeamLeaderboardMessage(gameID string) (*Message, error) {
+	standings, err := h.TeamStandings(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMessage("team_leaderboard", standings)
+}
+
+// teamsChanged tells the game about changed teams.
+func (h *Hub) teamsChanged(gameID string) {
+	h.BroadcastConnectedPlayers(gameID)
+	h.broadcastMessage(gameID, "team_leaderboard", h.TeamLeaderboardMessage)
+
+	if room, ok := h.rooms[gameID]; ok {
+		h.broadcastGames(room.Members)
+	}
+}
+
+// updateTeams loads the game named in a team command, lets change modify its
+// teams, and stores and announces the result.
+func (c *Connection) updateTeams(gameId string, change func(game *Game) error) (*Game, error) {
+	game, err := c.hub.store.FindGameById(gameId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = change(game)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.hub.store.UpdateGame(*game)
+	if err != nil {
+		return nil, err
+	}
+
+	c.hub.teamsChanged(game.ID)
+
+	return game, nil
+}
+
+func (c *Connection) CreateTeam(msg SocketMessage) (*Game, error) {
+	var payload CreateTeamPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	name := strings.TrimSpace(payload.Name)
+	if name == "" {
+		return nil, NewCommandError(ErrCodeInvalidPayload, "team needs a name")
+	}
+
+	return c.updateTeams(payload.GameID, func(game *Game) error {
+		game.Teams = append(game.Teams, Team{
+			ID:      uuid.New().String(),
+			Name:    name,
+			Players: []string{},
+		})
+
+		return nil
+	})
+}
+
+func (c *Connection) DeleteTeam(msg SocketMessage) (*Game, error) {
+	var payload TeamPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	return c.updateTeams(payload.GameID, func(game *Game) error {
+		if game.findTeam(payload.TeamID) == nil {
+			return ErrTeamNotFound
+		}
+
+		game.Teams = slices.DeleteFunc(game.Teams, func(t Team) bool {
+			return t.ID == payload.TeamID
+		})
+
+		return nil
+	})
+}
+
+// AssignPlayer moves a player of the game into a team, or out of every team
+// when no team is given.
+func (c *Connection) AssignPlayer(msg SocketMessage) (*Game, error) {
+	var payload TeamPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	return c.updateTeams(payload.GameID, func(game *Game) error {
+		if !slices.Contains(game.Players, payload.PlayerID) {
+			return ErrPlayerNotFound
+		}
+
+		if payload.TeamID == "" {
+			game.RemoveFromTeams(payload.PlayerID)
+			return nil
+		}
+
+		return game.AssignToTeam(payload.PlayerID, payload.TeamID)
+	})
+}
+
+func (c *Connection) SetTeamCaptain(msg SocketMessage) (*Game, error) {
+	var payload TeamPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	return c.updateTeams(payload.GameID, func(game *Game) error {
+		team := game.findTeam(payload.TeamID)
+		if team == nil {
+			return ErrTeamNotFound
+		}
+
+		if !slices.Contains(team.Players, payload.PlayerID) {
+			return ErrPlayerNotFound
+		}
+
+		team.CaptainID = payload.PlayerID
+
+		return nil
+	})
+}
+
+func (c *Connection) ShuffleTeams(msg SocketMessage) (*Game, error) {
+	var payload GameIDPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	return c.updateTeams(payload.GameID, func(game *Game) error {
+		if len(game.Teams) == 0 {
+			return ErrTeamNotFound
+		}
+
+		game.ShuffleTeams()
+
+		return nil
+	})
+}
+
+// SetAnswerMode chooses whether everybody or only the team captains answer
+// the active round. It can only change before the round opens.
+func (c *Connection) SetAnswerMode(msg SocketMessage) (*GameRound, error) {
+	var payload AnswerModePayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	if payload.Mode != AnswerIndividual && payload.Mode != AnswerCaptain {
+		return nil, NewCommandError(ErrCodeInvalidPayload, "unknown answer mode %q", payload.Mode)
+	}
+
+	round, err := c.GetActiveRound()
+	if err != nil {
+		return nil, err
+	}
+
+	if round.State != RoundDraft {
+		return nil, NewCommandError(ErrCodeInvalidRoundState, "the answer mode can only be changed before the round starts")
+	}
+
+	round.AnswerMode = payload.Mode
+
+	err = c.hub.store.UpdateRound(*round)
+	if err != nil {
+		return nil, err
+	}
+
+	c.hub.BroadcastRounds(round.GameID)
+
+	return round, nil
+}
+
+func (c *Connection) SendTeamLeaderboard() error {
+	game, err := c.GetActiveGame()
+	if err != nil {
+		return err
+	}
+
+	msg, err := c.hub.TeamLeaderboardMessage(game.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.Send(msg)
+}
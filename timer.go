@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+var ErrRoundClosed = NewCommandError(ErrCodeRoundClosed, "the time for this round is up")
+
+// roundTimer runs the clock of one timed round: it sends remaining time ticks
+// to the game and ends the round at its deadline. It belongs to the hub, not
+// to the moderator's connection, so it keeps running when they disconnect.
+type roundTimer struct {
+	gameID   string
+	roundID  string
+	deadline time.Time
+	tick     Timer
+	end      Timer
+}
+
+func (t *roundTimer) stop() {
+	t.tick.Stop()
+	t.end.Stop()
+}
+
+type RoundTimerPayload struct {
+	RoundID  string    `json:"roundId"`
+	Deadline time.Time `json:"deadline"`
+	// RemainingMs is the time left in milliseconds.
+	RemainingMs int64 `json:"remainingMs"`
+}
+
+// Expired reports whether the round has a deadline that has passed.
+func (r GameRound) Expired(now time.Time) bool {
+	return r.Deadline != nil && !now.Before(*r.Deadline)
+}
+
+// StartRoundTimer schedules the end of round at its deadline, replacing any
+// timer the game already had.
+func (h *Hub) StartRoundTimer(round GameRound) {
+	h.StopRoundTimer(round.GameID)
+
+	if round.Deadline == nil {
+		return
+	}
+
+	t := &roundTimer{
+		gameID:   round.GameID,
+		roundID:  round.ID,
+		deadline: *round.Deadline,
+	}
+
+	// Timer callbacks run on their own goroutine and queue the actual work
+	// on the hub. By then the timer may have been replaced, hence the check.
+	t.end = h.clock.AfterFunc(t.deadline.Sub(h.clock.Now()), func() {
+		h.commands <- func() {
+			if h.timers[t.gameID] == t {
+				h.expireRound(t)
+			}
+		}
+	})
+	t.tick = h.scheduleTick(t)
+
+	h.timers[round.GameID] = t
+	h.broadcastRoundTimer(t)
+}
+
+func (h *Hub) scheduleTick(t *roundTimer) Timer {
+	return h.clock.AfterFunc(h.config.RoundTickInterval, func() {
+		h.commands <- func() {
+			if h.timers[t.gameID] != t || !h.clock.Now().Before(t.deadline) {
+				return
+			}
+
+			h.broadcastRoundTimer(t)
+			t.tick = h.scheduleTick(t)
+		}
+	})
+}
+
+// StopRoundTimer cancels the timer of a game, if it has one.
+func (h *Hub) StopRoundTimer(gameID string) {
+	t, ok := h.timers[gameID]
+	if !ok {
+		return
+	}
+
+	t.stop()
+	delete(h.timers, gameID)
+}
+
+// RoundTimerMessage tells how much time is left in the round t runs.
+func (h *Hub) RoundTimerMessage(t *roundTimer) (*Message, error) {
+	remaining := max(t.deadline.Sub(h.clock.Now()), 0)
+
+	return NewMessage("round_timer", RoundTimerPayload{
+		RoundID:     t.roundID,
+		Deadline:    t.deadline,
+		RemainingMs: remaining.Milliseconds(),
+	})
+}
+
+func (h *Hub) broadcastRoundTimer(t *roundTimer) {
+	msg, err := h.RoundTimerMessage(t)
+	if err != nil {
+		log.Println("build round_timer:", err)
+		return
+	}
+
+	h.Broadcast(t.gameID, msg)
+}
+
+func (h *Hub) expireRound(t *roundTimer) {
+	h.StopRoundTimer(t.gameID)
+	h.broadcastRoundTimer(t)
+
+	round, err := h.store.FindRoundById(t.roundID)
+	if err != nil {
+		log.Println("expire round:", err)
+		return
+	}
+
+	if round.State != RoundOpen {
+		return
+	}
+
+	err = h.EndRound(*round)
+	if err != nil {
+		log.Println("expire round:", err)
+	}
+}
+
+// RestoreRoundTimers restarts the timers of rounds that were running when
+// the server stopped. Rounds whose deadline passed in the meantime end right
+// away.
+func (h *Hub) RestoreRoundTimers() error {
+	games, err := h.store.FindAllGames()
+	if err != nil {
+		return err
+	}
+
+	for _, game := range games {
+		round, err := h.store.FindActiveRoundByGameId(game.ID)
+		if errors.Is(err, ErrRoundNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if round.State == RoundOpen && round.Deadline != nil {
+			h.StartRoundTimer(*round)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type timedRound struct {
+	hub    *Hub
+	clock  *manualClock
+	mod    *testClient
+	player *testClient
+	gameID string
+}
+
+// startTimedRound starts a round of the given length in a new game with a
+// moderator and one player.
+func startTimedRound(t *testing.T, duration int) timedRound {
+	hub := newTestHub(t)
+	clock := useManualClock(hub)
+
+	mod := connect(t, hub, nil)
+	t.Cleanup(mod.close)
+	mod.mustHello("Mod")
+
+	var game Game
+	mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &game)
+
+	player := connect(t, hub, nil)
+	t.Cleanup(player.close)
+	player.mustHello("Player")
+	player.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+
+	mod.mustRequest("start_round", map[string]any{"gameId": game.ID, "duration": duration}, nil)
+
+	return timedRound{hub, clock, mod, player, game.ID}
+}
+
+func (r timedRound) round(t *testing.T) GameRound {
+	var round *GameRound
+	var err error
+
+	r.hub.Do(func() {
+		round, err = r.hub.store.FindActiveRoundByGameId(r.gameID)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return *round
+}
+
+func (r timedRound) remainingMs(t *testing.T) []int64 {
+	res := []int64{}
+
+	for _, msg := range r.player.messages("round_timer") {
+		var payload RoundTimerPayload
+		err := json.Unmarshal(msg.Payload, &payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res = append(res, payload.RemainingMs)
+	}
+
+	return res
+}
+
+func TestRoundTimerTicks(t *testing.T) {
+	r := startTimedRound(t, 12)
+
+	for range 3 {
+		advance(r.hub, r.clock, r.hub.config.RoundTickInterval)
+	}
+
+	// Wait for the broadcasts to be written.
+	r.player.waitFor(func(m SocketMessage) bool {
+		return len(r.remainingMs(t)) >= 4
+	})
+
+	got := r.remainingMs(t)
+	want := []int64{12000, 7000, 2000, 0}
+
+	if len(got) != len(want) {
+		t.Fatalf("remaining times %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("remaining times %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRoundEndsAtDeadline(t *testing.T) {
+	r := startTimedRound(t, 30)
+
+	advance(r.hub, r.clock, 29*time.Second)
+
+	if state := r.round(t).State; state != RoundOpen {
+		t.Fatalf("round is %s before its deadline", state)
+	}
+
+	advance(r.hub, r.clock, time.Second)
+
+	if state := r.round(t).State; state != RoundClosed {
+		t.Fatalf("round is %s after its deadline, want closed", state)
+	}
+}
+
+func TestSetAnswerRejectedAfterDeadline(t *testing.T) {
+	r := startTimedRound(t, 30)
+
+	r.player.mustRequest("set_answer", TextPayload{Text: "in time"}, nil)
+
+	// The deadline counts even before the timer ending the round ran.
+	r.clock.Set(r.clock.Now().Add(30 * time.Second))
+
+	_, err := r.player.request("set_answer", TextPayload{Text: "too late"})
+
+	var reply *replyError
+	if !errors.As(err, &reply) || reply.Code != ErrCodeRoundClosed {
+		t.Fatalf("late answer: got %v, want %s", err, ErrCodeRoundClosed)
+	}
+
+	advance(r.hub, r.clock, 0)
+
+	_, err = r.player.request("set_answer", TextPayload{Text: "even later"})
+	if !errors.As(err, &reply) || reply.Code != ErrCodeRoundClosed {
+		t.Fatalf("answer to the ended round: got %v, want %s", err, ErrCodeRoundClosed)
+	}
+}
+
+func TestRestoreRoundTimers(t *testing.T) {
+	hub := newTestHub(t)
+	clock := useManualClock(hub)
+
+	now := clock.Now()
+	passed := now.Add(-time.Minute)
+	running := now.Add(time.Minute)
+
+	hub.Do(func() {
+		hub.store.CreateGame(Game{ID: "passed", ModeratorUUID: "mod"})
+		hub.store.CreateGame(Game{ID: "running", ModeratorUUID: "mod"})
+		hub.store.CreateGame(Game{ID: "untimed", ModeratorUUID: "mod"})
+		hub.store.CreateRound(GameRound{ID: "r1", GameID: "passed", State: RoundOpen, Deadline: &passed})
+		hub.store.CreateRound(GameRound{ID: "r2", GameID: "running", State: RoundOpen, Deadline: &running})
+		hub.store.CreateRound(GameRound{ID: "r3", GameID: "untimed", State: RoundOpen})
+	})
+
+	var err error
+	hub.Do(func() {
+		err = hub.RestoreRoundTimers()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := func(id string) RoundState {
+		var round *GameRound
+		hub.Do(func() {
+			round, err = hub.store.FindRoundById(id)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return round.State
+	}
+
+	advance(hub, clock, 0)
+
+	if state("r1") != RoundClosed || state("r2") != RoundOpen || state("r3") != RoundOpen {
+		t.Fatalf("right after restoring: %s, %s, %s", state("r1"), state("r2"), state("r3"))
+	}
+
+	advance(hub, clock, time.Minute)
+
+	if state("r2") != RoundClosed || state("r3") != RoundOpen {
+		t.Fatalf("after the running deadline: %s, %s", state("r2"), state("r3"))
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"log"
+	"math/rand/v2"
+	"slices"
+)
+
+var (
+	ErrVotingClosed = NewCommandError(ErrCodeVotingClosed, "voting is not open")
+	ErrSelfVote     = NewCommandError(ErrCodeSelfVote, "you cannot vote for your own answer")
+	ErrNoVotesLeft  = NewCommandError(ErrCodeNoVotesLeft, "no votes left")
+	ErrAlreadyVoted = NewCommandError(ErrCodeAlreadyVoted, "you already voted for this answer")
+)
+
+// Voting is the phase after a round ended in which players vote for the
+// revealed answers of the others.
+type Voting struct {
+	Open           bool `bson:"open" json:"open"`
+	VotesPerPlayer int  `bson:"votesPerPlayer" json:"votesPerPlayer"`
+	// PointsPerVote is added to an answer's score for every vote it gets
+	// once voting ends.
+	PointsPerVote int `bson:"pointsPerVote" json:"pointsPerVote"`
+	// Votes maps each voter to the answers they voted for. Who voted for
+	// what stays on the server.
+	Votes map[string][]string `bson:"votes" json:"-"`
+}
+
+// Tally counts the votes per answer ID.
+func (v Voting) Tally() map[string]int {
+	tally := map[string]int{}
+
+	for _, answerIds := range v.Votes {
+		for _, id := range answerIds {
+			tally[id]++
+		}
+	}
+
+	return tally
+}
+
+type StartVotingPayload struct {
+	VotesPerPlayer *int `json:"votesPerPlayer"`
+	PointsPerVote  *int `json:"pointsPerVote"`
+}
+
+// BallotEntry is an answer as shown for voting, without its author.
+type BallotEntry struct {
+	AnswerID string `json:"answerId"`
+	Text     string `json:"text"`
+	Votes    int    `json:"votes"`
+}
+
+type BallotPayload struct {
+	RoundID        string        `json:"roundId"`
+	Open           bool          `json:"open"`
+	VotesPerPlayer int           `json:"votesPerPlayer"`
+	Answers        []BallotEntry `json:"answers"`
+}
+
+// VoteResult is an answer with its author and votes, sent when voting ends.
+type VoteResult struct {
+	AnswerID string `json:"answerId"`
+	PlayerID string `json:"playerId"`
+	Text     string `json:"text"`
+	Votes    int    `json:"votes"`
+	Points   int    `json:"points"`
+}
+
+type VoteResultsPayload struct {
+	RoundID string       `json:"roundId"`
+	Results []VoteResult `json:"results"`
+}
+
+// ballotAnswers returns the answers of round that can be voted for: the
+// ones revealed to the players.
+func (h *Hub) ballotAnswers(round GameRound) ([]Answer, error) {
+	answers, err := h.store.FindAllAnswersByGameAndRound(round.GameID, round.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(answers, func(a Answer) bool {
+		return !a.RevealedToPlayers
+	}), nil
+}
+
+// BallotMessage lists the answers up for vote in random order, so the order
+// answers came in does not give their authors away.
+func (h *Hub) BallotMessage(round GameRound) (*Message, error) {
+	answers, err := h.ballotAnswers(round)
+	if err != nil {
+		return nil, err
+	}
+
+	tally := round.Voting.Tally()
+
+	entries := []BallotEntry{}
+	for _, a := range answers {
+		entries = append(entries, BallotEntry{
+			AnswerID: a.ID,
+			Text:     round.AnswerText(a),
+			Votes:    tally[a.ID],
+		})
+	}
+
+	rand.Shuffle(len(entries), func(i, j int) {
+		entries[i], entries[j] = entries[j], entries[i]
+	})
+
+	return NewMessage("ballot", BallotPayload{
+		RoundID:        round.ID,
+		Open:           round.Voting.Open,
+		VotesPerPlayer: round.Voting.VotesPerPlayer,
+		Answers:        entries,
+	})
+}
+
+func (h *Hub) BroadcastBallot(round GameRound) {
+	msg, err := h.BallotMessage(round)
+	if err != nil {
+		log.Println("build ballot:", err)
+		return
+	}
+
+	h.Broadcast(round.GameID, msg)
+}
+
+// RefreshBallot sends the ballot again if a vote on round is open, after the
+// answers on it changed.
+func (h *Hub) RefreshBallot(roundId string) {
+	round, err := h.store.FindRoundById(roundId)
+	if err != nil {
+		log.Println("find round:", err)
+		return
+	}
+
+	if round.Voting != nil && round.Voting.Open {
+		h.BroadcastBallot(*round)
+	}
+}
+
+// StartVoting opens the vote on the revealed answers of the ended active
+// round.
+func (c *Connection) StartVoting(msg SocketMessage) (*Voting, error) {
+	var payload StartVotingPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return nil, InvalidPayload(err)
+	}
+
+	round, err := c.GetActiveRound()
+	if err != nil {
+		return nil, err
+	}
+
+	if round.State != RoundClosed && round.State != RoundRevealed {
+		return nil, NewCommandError(ErrCodeInvalidRoundState, "voting starts after the round ended")
+	}
+
+	voting := &Voting{
+		Open:           true,
+		VotesPerPlayer: c.hub.config.VotesPerPlayer,
+		PointsPerVote:  c.hub.config.PointsPerVote,
+		Votes:          map[string][]string{},
+	}
+
+	if payload.VotesPerPlayer != nil {
+		voting.VotesPerPlayer = *payload.VotesPerPlayer
+	}
+
+	if payload.PointsPerVote != nil {
+		voting.PointsPerVote = *payload.PointsPerVote
+	}
+
+	if voting.VotesPerPlayer < 1 {
+		return nil, NewCommandError(ErrCodeInvalidPayload, "votesPerPlayer must be at least 1")
+	}
+
+	round.Voting = voting
+
+	err = c.hub.store.UpdateRound(*round)
+	if err != nil {
+		return nil, err
+	}
+
+	c.hub.BroadcastBallot(*round)
+	c.hub.BroadcastAnswers(round.GameID)
+
+	return voting, nil
+}
+
+// Vote casts one of the player's votes for another player's answer.
+func (c *Connection) Vote(msg SocketMessage) error {
+	var payload AnswerIDPayload
+	err := msg.Decode(&payload)
+	if err != nil {
+		return InvalidPayload(err)
+	}
+
+	player, err := c.GetPlayer()
+	if err != nil {
+		return err
+	}
+
+	round, err := c.GetActiveRound()
+	if err != nil {
+		return err
+	}
+
+	if round.Voting == nil || !round.Voting.Open {
+		return ErrVotingClosed
+	}
+
+	answer, err := c.hub.store.FindAnswerById(payload.AnswerID)
+	if err != nil {
+		return err
+	}
+
+	if answer.RoundID != round.ID || !answer.RevealedToPlayers {
+		return ErrAnswerNotFound
+	}
+
+	if answer.PlayerID == player.ID {
+		return ErrSelfVote
+	}
+
+	votes := round.Voting.Votes[player.ID]
+
+	if slices.Contains(votes, answer.ID) {
+		return ErrAlreadyVoted
+	}
+
+	if len(votes) >= round.Voting.VotesPerPlayer {
+		return ErrNoVotesLeft
+	}
+
+	if round.Voting.Votes == nil {
+		round.Voting.Votes = map[string][]string{}
+	}
+
+	round.Voting.Votes[player.ID] = append(votes, answer.ID)
+
+	err = c.hub.store.UpdateRound(*round)
+	if err != nil {
+		return err
+	}
+
+	c.hub.BroadcastBallot(*round)
+
+	return nil
+}
+
+// EndVoting closes the vote, scores the answers by their votes and publishes
+// who wrote what.
+func (c *Connection) EndVoting() error {
+	round, err := c.GetActiveRound()
+	if err != nil {
+		return err
+	}
+
+	if round.Voting == nil || !round.Voting.Open {
+		return ErrVotingClosed
+	}
+
+	round.Voting.Open = false
+
+	err = c.hub.store.UpdateRound(*round)
+	if err != nil {
+		return err
+	}
+
+	answers, err := c.hub.ballotAnswers(*round)
+	if err != nil {
+		return err
+	}
+
+	tally := round.Voting.Tally()
+
+	results := []VoteResult{}
+	for _, a := range answers {
+		a.Votes = tally[a.ID]
+		a.VotePoints = a.Votes * round.Voting.PointsPerVote
+
+		err = c.hub.store.UpdateAnswer(a)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, VoteResult{
+			AnswerID: a.ID,
+			PlayerID: a.PlayerID,
+			Text:     round.AnswerText(a),
+			Votes:    a.Votes,
+			Points:   a.VotePoints,
+		})
+	}
+
+	slices.SortStableFunc(results, func(a, b VoteResult) int {
+		return b.Votes - a.Votes
+	})
+
+	msg, err := NewMessage("vote_results", VoteResultsPayload{
+		RoundID: round.ID,
+		Results: results,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.hub.Broadcast(round.GameID, msg)
+	c.hub.BroadcastAnswers(round.GameID)
+	c.hub.BroadcastLeaderboard(round.GameID)
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Players must not learn who wrote which answer before the vote is over,
+// whether the answers are revealed before or after voting starts.
+func TestVoteIsAnonymous(t *testing.T) {
+	orders := map[string][]string{
+		"reveal first": {"reveal_round", "start_voting"},
+		"vote first":   {"start_voting", "reveal_round"},
+	}
+
+	for name, steps := range orders {
+		t.Run(name, func(t *testing.T) {
+			hub := newTestHub(t)
+
+			mod := connect(t, hub, nil)
+			defer mod.close()
+			mod.mustHello("Mod")
+
+			var game Game
+			mod.mustRequest("create_game", CreateGamePayload{Name: "Quiz"}, &game)
+			mod.mustRequest("start_round", GameIDPayload{GameID: game.ID}, nil)
+
+			ann := connect(t, hub, nil)
+			defer ann.close()
+			ann.mustHello("Ann")
+
+			bob := connect(t, hub, nil)
+			defer bob.close()
+			bobID := bob.mustHello("Bob")
+
+			for _, p := range []*testClient{ann, bob} {
+				p.mustRequest("join_game", JoinGamePayload{GameID: game.ID}, nil)
+			}
+
+			ann.mustRequest("set_answer", TextPayload{Text: "Ann's answer"}, nil)
+			bob.mustRequest("set_answer", TextPayload{Text: "Bob's answer"}, nil)
+
+			mod.mustRequest("end_round", GameIDPayload{GameID: game.ID}, nil)
+
+			for _, step := range steps {
+				mod.mustRequest(step, GameIDPayload{GameID: game.ID}, nil)
+			}
+
+			// Ann's own requests come after everything queued for her.
+			ann.mustRequest("get_text", GameIDPayload{GameID: game.ID}, nil)
+
+			ballot, ok := ann.last("ballot")
+			if !ok {
+				t.Fatal("got no ballot")
+			}
+
+			var payload BallotPayload
+			err := json.Unmarshal(ballot.Payload, &payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(payload.Answers) != 2 {
+				t.Fatalf("the last ballot has %d answers, want 2", len(payload.Answers))
+			}
+
+			authorOfBobs := func() string {
+				msg, _ := ann.last("all_answers")
+
+				var answers []Answer
+				err := json.Unmarshal(msg.Payload, &answers)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, a := range answers {
+					if a.Text == "Bob's answer" {
+						return a.PlayerID
+					}
+				}
+
+				t.Fatalf("Bob's answer was not revealed: %s", msg.Payload)
+				return ""
+			}
+
+			for _, msg := range ann.messages("all_answers") {
+				var answers []Answer
+				json.Unmarshal(msg.Payload, &answers)
+
+				for _, a := range answers {
+					if a.PlayerID == bobID && a.Text != "" {
+						t.Fatalf("Ann learned which answer is Bob's before the vote: %s", msg.Payload)
+					}
+				}
+			}
+
+			if author := authorOfBobs(); author != "" {
+				t.Fatalf("Bob's answer names %q during the vote", author)
+			}
+
+			mod.mustRequest("end_voting", GameIDPayload{GameID: game.ID}, nil)
+			ann.mustRequest("get_text", GameIDPayload{GameID: game.ID}, nil)
+
+			if author := authorOfBobs(); author != bobID {
+				t.Fatalf("Bob's answer names %q after the vote, want Bob", author)
+			}
+		})
+	}
+}